@@ -0,0 +1,281 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// AlertInformation - alert settings sent by the user
+type AlertInformation struct {
+	Symbol          string   `json:"symbol"`
+	Condition       string   `json:"condition"`
+	Threshold       *float64 `json:"threshold"`
+	WindowMinutes   *int     `json:"window_minutes"`
+	CooldownMinutes *int     `json:"cooldown_minutes"`
+	ChannelID       *int     `json:"channelid"`
+	Rearm           *bool    `json:"rearm"`
+	Enabled         *bool    `json:"enabled"`
+}
+
+// applyAlertNumbers - set threshold, time window and cooldown that were sent, others are kept
+func applyAlertNumbers(alert *models.Alert, alertInformation *AlertInformation) {
+	if alertInformation.Threshold != nil {
+		alert.Threshold = *alertInformation.Threshold
+	}
+
+	if alertInformation.WindowMinutes != nil {
+		alert.WindowMinutes = *alertInformation.WindowMinutes
+	}
+
+	if alertInformation.CooldownMinutes != nil {
+		alert.CooldownMinutes = *alertInformation.CooldownMinutes
+	}
+}
+
+// GetAlerts - get user's alerts and their latest firings
+func GetAlerts(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Alerts  []models.Alert       `json:"alerts"`
+			Firings []models.AlertFiring `json:"firings"`
+		}
+
+		alerts := models.GetUserAlerts(DB, userID)
+
+		if alerts == nil {
+			alerts = []models.Alert{}
+		}
+
+		firings := models.GetUserAlertFirings(DB, userID, 50)
+
+		if firings == nil {
+			firings = []models.AlertFiring{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Alerts: alerts, Firings: firings}, "success", 200)
+
+		return
+	}
+}
+
+// AddAlert - add new alert
+func AddAlert(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		alertInformation := &AlertInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(alertInformation)
+
+		if err != nil || alertInformation.Condition == "" || (alertInformation.Symbol == "" && !models.IsPortfolioAlert(alertInformation.Condition)) {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		alert := models.Alert{
+			Symbol:    alertInformation.Symbol,
+			Condition: alertInformation.Condition,
+			Rearm:     alertInformation.Rearm == nil || *alertInformation.Rearm,
+			Enabled:   alertInformation.Enabled == nil || *alertInformation.Enabled,
+		}
+
+		applyAlertNumbers(&alert, alertInformation)
+
+		if err = models.ValidateAlert(alert); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if models.IsPortfolioAlert(alert.Condition) {
+			// Portfolio alerts are checked against totals, not a coin
+			alert.Symbol = ""
+		} else {
+			alert.CoinID = models.GetCoinIDBySymbol(DB, alert.Symbol)
+
+			if alert.CoinID < 1 {
+				response := "This coin does not exist!"
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+		}
+
+		if alertInformation.ChannelID != nil {
+			if *alertInformation.ChannelID > 0 && models.GetUserChannel(DB, userID, *alertInformation.ChannelID).ChannelID < 1 {
+				response := "This notification channel does not belong to you!"
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			alert.ChannelID = *alertInformation.ChannelID
+		}
+
+		if models.CreateAlert(DB, userID, alert) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Alert has been added successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// EditAlert - edit alert
+func EditAlert(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		alertID, err := strconv.Atoi(vars["alertid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		alertInformation := &AlertInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(alertInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		alert := models.GetUserAlert(DB, userID, alertID)
+
+		if alert.AlertID < 1 {
+			response := "This alert does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if alertInformation.Condition != "" {
+			if models.IsPortfolioAlert(alertInformation.Condition) != models.IsPortfolioAlert(alert.Condition) {
+				response := "Coin alert can not be changed to portfolio alert or the other way around."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			alert.Condition = alertInformation.Condition
+		}
+
+		applyAlertNumbers(&alert, alertInformation)
+
+		if alertInformation.Rearm != nil {
+			alert.Rearm = *alertInformation.Rearm
+		}
+
+		if alertInformation.ChannelID != nil {
+			if *alertInformation.ChannelID > 0 && models.GetUserChannel(DB, userID, *alertInformation.ChannelID).ChannelID < 1 {
+				response := "This notification channel does not belong to you!"
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			alert.ChannelID = *alertInformation.ChannelID
+		}
+
+		if alertInformation.Enabled != nil {
+			alert.Enabled = *alertInformation.Enabled
+		}
+
+		if err = models.ValidateAlert(alert); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		if models.UpdateAlert(DB, userID, alert) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Alert has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeleteAlert - delete alert
+func DeleteAlert(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		alertID, err := strconv.Atoi(vars["alertid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if !models.RemoveAlert(DB, userID, alertID) {
+			response := "This alert does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Alert has been deleted successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
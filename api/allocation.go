@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetAllocation - get portfolio worth by coin, location and tag, compared with target weights when they are set
+func GetAllocation(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.Allocation
+		}
+
+		coins, syncInfo := models.GetUserCoins(portfolio.OwnerID, portfolio.PortfolioID, DB)
+
+		coins, _, currency := models.ConvertCoins(DB, coins, syncInfo, portfolio.Currency)
+
+		allocation := models.BuildAllocation(coins, models.GetHoldingTags(DB, portfolio.OwnerID), models.GetAllocationTargets(DB, portfolio.OwnerID, portfolio.PortfolioID))
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, Allocation: allocation}, "success", 200)
+
+		return
+	}
+}
+
+// UpdateAllocationTargets - replace target weights of the portfolio, empty list removes them
+func UpdateAllocationTargets(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		// TargetsInformation - target weights sent by the user
+		type TargetsInformation struct {
+			Targets []models.AllocationTarget `json:"targets"`
+		}
+
+		targetsInformation := &TargetsInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(targetsInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "editor")
+
+		if !ok {
+			return
+		}
+
+		if err = models.ValidateAllocationTargets(DB, targetsInformation.Targets); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		models.SetAllocationTargets(DB, portfolio.OwnerID, portfolio.PortfolioID, targetsInformation.Targets)
+
+		response := "Target allocation has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// GetRebalance - get trades that bring the portfolio to its target weights
+// Optional min_trade skips smaller trades and fee is estimated trading fee in percent, values are in the portfolio currency.
+func GetRebalance(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		var minTrade, fee float64
+
+		for _, field := range []struct {
+			Value  string
+			Target *float64
+		}{
+			{r.URL.Query().Get("min_trade"), &minTrade},
+			{r.URL.Query().Get("fee"), &fee},
+		} {
+			if field.Value == "" {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(field.Value, 64)
+
+			if err != nil || value < 0 {
+				response := "Please provide valid numbers."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			*field.Target = value
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		targets := models.GetAllocationTargets(DB, portfolio.OwnerID, portfolio.PortfolioID)
+
+		if len(targets) == 0 {
+			response := "Please set target allocation for this portfolio first."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.Rebalance
+		}
+
+		coins, syncInfo := models.GetUserCoins(portfolio.OwnerID, portfolio.PortfolioID, DB)
+
+		rate, currency := models.ConversionRate(DB, portfolio.Currency)
+
+		coins, _, currency = models.ConvertCoins(DB, coins, syncInfo, currency)
+
+		rebalance := models.BuildRebalance(DB, coins, targets, minTrade, fee, rate)
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, Rebalance: rebalance}, "success", 200)
+
+		return
+	}
+}
@@ -103,6 +103,8 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	DB := helpers.InitDB()
 
+	defer DB.Close()
+
 	countUsers := models.CountUsersWithEmailAddress(DB, user.Email)
 
 	if countUsers > 0 {
@@ -114,14 +116,14 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 	lastInsertID := models.CreateUser(DB, user.Email, user.Password)
 
-	defer DB.Close()
-
 	if lastInsertID < 1 {
 		helpers.DefaultErrorRespond(w, r)
 
 		return
 	}
 
+	models.CreateDefaultPortfolio(DB, lastInsertID)
+
 	generateJWT := helpers.GenerateJWT(lastInsertID)
 
 	if generateJWT != "" {
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// RunBacktest - simulate lump sum, DCA, periodic or threshold rebalancing strategy over stored price history
+// Amounts and prices are in EUR, nothing is saved.
+func RunBacktest(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		backtest := models.Backtest{}
+
+		err := json.NewDecoder(r.Body).Decode(&backtest)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if err = models.ValidateBacktest(DB, backtest); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		result, err := models.RunBacktest(DB, backtest)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		helpers.Respond(w, r, result, "success", 200)
+
+		return
+	}
+}
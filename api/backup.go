@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetBackup - download backup of user's account
+func GetBackup(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		backup := models.CreateBackup(DB, userID)
+
+		file, err := json.MarshalIndent(backup, "", "  ")
+
+		if err != nil {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		fileName := "backup-" + time.Now().Format("2006-01-02") + ".json"
+
+		helpers.RespondFile(w, r, fileName, "application/json; charset=UTF-8", file)
+
+		return
+	}
+}
+
+// RestoreBackup - restore backup into an empty account or merge it into existing one
+func RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		mode := r.URL.Query().Get("mode")
+
+		if mode == "" {
+			mode = "empty"
+		}
+
+		if mode != "empty" && mode != "merge" {
+			response := "Restore mode has to be empty or merge."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		backup := models.Backup{}
+
+		err := json.NewDecoder(r.Body).Decode(&backup)
+
+		if err != nil {
+			response := "This is not a valid backup file."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateBackup(backup); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if mode == "empty" && !models.AccountIsEmpty(DB, userID) {
+			response := "Your account is not empty. Restore in merge mode instead."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		restoreResult := models.RestoreBackup(DB, userID, backup, mode == "merge")
+
+		helpers.Respond(w, r, restoreResult, "success", 200)
+
+		return
+	}
+}
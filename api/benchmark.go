@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetBenchmark - compare portfolio with the same cash flows put into a benchmark coin or weighted basket
+// Benchmark is a symbol like BTC or a basket like BTC:60,ETH:40, values are in the portfolio currency.
+func GetBenchmark(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		period := r.URL.Query().Get("period")
+
+		if period == "" {
+			period = "all"
+		}
+
+		if err := models.ValidatePerformancePeriod(period); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		benchmarkValue := r.URL.Query().Get("benchmark")
+
+		if benchmarkValue == "" {
+			benchmarkValue = "BTC"
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		benchmark, err := models.ParseBenchmark(DB, benchmarkValue)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		comparison, err := models.CompareWithBenchmark(DB, portfolio.OwnerID, portfolio.PortfolioID, benchmark, period)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.BenchmarkComparison
+		}
+
+		comparison, currency := models.ConvertBenchmarkComparison(DB, comparison, portfolio.Currency)
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, BenchmarkComparison: comparison}, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// ChannelInformation - notification channel settings sent by the user
+type ChannelInformation struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	ChatID  string `json:"chat_id"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// GetChannels - get user's notification channels
+func GetChannels(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Channels []models.NotificationChannel `json:"channels"`
+			Types    []string                     `json:"types"`
+		}
+
+		channels := models.GetUserChannels(DB, userID)
+
+		if channels == nil {
+			channels = []models.NotificationChannel{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Channels: channels, Types: models.ChannelTypes}, "success", 200)
+
+		return
+	}
+}
+
+// AddChannel - add new notification channel
+func AddChannel(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		channelInformation := &ChannelInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(channelInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		channel := models.NotificationChannel{
+			Type:    channelInformation.Type,
+			Name:    channelInformation.Name,
+			URL:     channelInformation.URL,
+			ChatID:  channelInformation.ChatID,
+			Enabled: channelInformation.Enabled == nil || *channelInformation.Enabled,
+		}
+
+		if err = models.ValidateChannel(channel); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		channel.ChannelID = models.CreateChannel(DB, userID, channel)
+
+		if channel.ChannelID < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		helpers.Respond(w, r, channel, "success", 200)
+
+		return
+	}
+}
+
+// EditChannel - edit notification channel
+func EditChannel(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		channelID, err := strconv.Atoi(mux.Vars(r)["channelid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		channelInformation := &ChannelInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(channelInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		channel := models.GetUserChannel(DB, userID, channelID)
+
+		if channel.ChannelID < 1 {
+			response := "This notification channel does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		for _, field := range []struct {
+			Value  string
+			Target *string
+		}{
+			{channelInformation.Type, &channel.Type},
+			{channelInformation.Name, &channel.Name},
+			{channelInformation.URL, &channel.URL},
+			{channelInformation.ChatID, &channel.ChatID},
+		} {
+			if field.Value != "" {
+				*field.Target = field.Value
+			}
+		}
+
+		if channelInformation.Enabled != nil {
+			channel.Enabled = *channelInformation.Enabled
+		}
+
+		if err = models.ValidateChannel(channel); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		if models.UpdateChannel(DB, userID, channel) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Notification channel has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeleteChannel - delete notification channel, alerts and digest using it stop posting to chat
+func DeleteChannel(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		channelID, err := strconv.Atoi(mux.Vars(r)["channelid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if !models.RemoveChannel(DB, userID, channelID) {
+			response := "This notification channel does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Notification channel has been deleted successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// GetChannelMessages - get latest messages posted to the channel
+func GetChannelMessages(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		channelID, err := strconv.Atoi(mux.Vars(r)["channelid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if models.GetUserChannel(DB, userID, channelID).ChannelID < 1 {
+			response := "This notification channel does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Messages []models.ChannelMessage `json:"messages"`
+		}
+
+		messages := models.GetChannelMessages(DB, channelID, 100)
+
+		if messages == nil {
+			messages = []models.ChannelMessage{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Messages: messages}, "success", 200)
+
+		return
+	}
+}
+
+// TestChannel - post test message to the channel right away and return the outcome
+func TestChannel(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		channelID, err := strconv.Atoi(mux.Vars(r)["channelid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		channel := models.GetUserChannel(DB, userID, channelID)
+
+		if channel.ChannelID < 1 {
+			response := "This notification channel does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !channel.Enabled {
+			response := "This notification channel is disabled."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		helpers.Respond(w, r, models.SendChannelTest(DB, userID, channel.ChannelID), "success", 200)
+
+		return
+	}
+}
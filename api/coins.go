@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetCoinPrice - get coin price at given time from price history
+func GetCoinPrice(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	coinID, err := strconv.Atoi(vars["coinid"])
+
+	if err != nil {
+		response := "This coin does not exist!"
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return
+	}
+
+	at := time.Now()
+
+	if r.URL.Query().Get("at") != "" {
+		at, err = helpers.ParseDateTime(r.URL.Query().Get("at"))
+
+		if err != nil {
+			response := "Please provide a valid date."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+	}
+
+	DB := helpers.InitDB()
+
+	defer DB.Close()
+
+	coin := models.GetCoinByID(DB, coinID)
+
+	if coin.Name == "" {
+		response := "This coin does not exist!"
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return
+	}
+
+	price, ok := models.GetCoinPriceAt(DB, coinID, at)
+
+	if !ok {
+		response := "There is no price history for this coin at this date."
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return
+	}
+
+	type ResponseSuccessData struct {
+		Coin     models.CoinSymbolInfo `json:"coin"`
+		At       string                `json:"at"`
+		PriceEur float64               `json:"priceeur"`
+	}
+
+	helpers.Respond(w, r, ResponseSuccessData{Coin: coin, At: helpers.FormatDateTime(at), PriceEur: price}, "success", 200)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetDigest - get user's digest settings
+func GetDigest(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Settings    models.DigestSettings `json:"settings"`
+			Frequencies []string              `json:"frequencies"`
+			Channels    []string              `json:"channels"`
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{
+			Settings:    models.GetDigestSettings(DB, userID),
+			Frequencies: models.DigestFrequencies,
+			Channels:    models.DigestChannels,
+		}, "success", 200)
+
+		return
+	}
+}
+
+// UpdateDigest - update user's digest settings, fields that are not sent stay the same
+func UpdateDigest(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		// DigestInformation - digest settings sent by the user
+		type DigestInformation struct {
+			Frequency string `json:"frequency"`
+			Time      string `json:"time"`
+			Weekday   string `json:"weekday"`
+			Timezone  string `json:"timezone"`
+			Channel   string `json:"channel"`
+			ChannelID *int   `json:"channelid"`
+		}
+
+		digestInformation := &DigestInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(digestInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		digestSettings := models.GetDigestSettings(DB, userID)
+
+		for _, field := range []struct {
+			Value  string
+			Target *string
+		}{
+			{digestInformation.Frequency, &digestSettings.Frequency},
+			{digestInformation.Time, &digestSettings.Time},
+			{digestInformation.Weekday, &digestSettings.Weekday},
+			{digestInformation.Timezone, &digestSettings.Timezone},
+			{digestInformation.Channel, &digestSettings.Channel},
+		} {
+			if field.Value != "" {
+				*field.Target = field.Value
+			}
+		}
+
+		if digestInformation.ChannelID != nil {
+			if *digestInformation.ChannelID > 0 && models.GetUserChannel(DB, userID, *digestInformation.ChannelID).ChannelID < 1 {
+				response := "This notification channel does not belong to you!"
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			digestSettings.ChannelID = *digestInformation.ChannelID
+		}
+
+		if err = models.ValidateDigestSettings(digestSettings); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		models.SetDigestSettings(DB, userID, digestSettings)
+
+		response := "Digest settings have been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// PreviewDigest - build digest as it would be sent now, nothing is delivered
+func PreviewDigest(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		digestSettings := models.GetDigestSettings(DB, userID)
+
+		if digestSettings.Frequency == "off" {
+			digestSettings.Frequency = "daily"
+		}
+
+		helpers.Respond(w, r, models.BuildDigest(DB, userID, digestSettings), "success", 200)
+
+		return
+	}
+}
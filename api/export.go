@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// Export - export holdings, transactions, snapshots or report as CSV, JSON or XLSX
+func Export(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+		dataset := vars["dataset"]
+
+		datasetExists := false
+
+		for _, exportDataset := range models.ExportDatasets {
+			if dataset == exportDataset {
+				datasetExists = true
+			}
+		}
+
+		if !datasetExists {
+			response := "This data can not be exported."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+
+		if format == "" {
+			format = "csv"
+		}
+
+		if format != "csv" && format != "json" && format != "xlsx" {
+			response := "Export format has to be csv, json or xlsx."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		data, table := models.GetExportData(DB, portfolio.OwnerID, portfolio.PortfolioID, dataset)
+
+		fileName := dataset + "-" + time.Now().Format("2006-01-02") + "." + format
+
+		var file []byte
+		var contentType string
+		var err error
+
+		switch format {
+		case "json":
+			contentType = "application/json; charset=UTF-8"
+			file, err = json.MarshalIndent(data, "", "  ")
+		case "xlsx":
+			contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+			file, err = helpers.WriteXLSX(dataset, table)
+		default:
+			contentType = "text/csv; charset=UTF-8"
+			file, err = helpers.WriteCSV(table)
+		}
+
+		if err != nil {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		helpers.RespondFile(w, r, fileName, contentType, file)
+
+		return
+	}
+}
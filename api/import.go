@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// ImportTransactions - import transactions from exchange or portfolio tracker CSV export
+func ImportTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		// ImportInformation - CSV export and how to read it
+		type ImportInformation struct {
+			Format  string                     `json:"format"`
+			Lives   string                     `json:"lives"`
+			DryRun  bool                       `json:"dryrun"`
+			Mapping models.ImportColumnMapping `json:"mapping"`
+			Symbols map[string]string          `json:"symbols"`
+			Data    string                     `json:"data"`
+		}
+
+		importInformation := &ImportInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(importInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if importInformation.Format == "" || importInformation.Data == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !models.ImportFormatExists(importInformation.Format) {
+			response := "This import format is not supported."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, false, "editor")
+
+		if !ok {
+			return
+		}
+
+		importResult := models.ImportTransactions(DB, portfolio.OwnerID, portfolio.PortfolioID, importInformation.Format, strings.NewReader(importInformation.Data),
+			importInformation.Mapping, importInformation.Symbols, importInformation.Lives, importInformation.DryRun)
+
+		if importResult.Imported > 0 {
+			models.NotifyUser(DB, portfolio.OwnerID, "holding.changed", models.HoldingChangedEvent{Action: "imported", Count: importResult.Imported})
+		}
+
+		helpers.Respond(w, r, importResult, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,375 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetPortfolioMembers - get users the portfolio is shared with, owners also get pending invitations
+func GetPortfolioMembers(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		portfolioID, err := strconv.Atoi(mux.Vars(r)["portfolioid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "viewer")
+
+		if !ok {
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Members     []models.PortfolioMember     `json:"members"`
+			Invitations []models.PortfolioInvitation `json:"invitations"`
+			Roles       []string                     `json:"roles"`
+		}
+
+		invitations := []models.PortfolioInvitation{}
+
+		if models.PortfolioRoleAllows(portfolio.Role, "owner") {
+			invitations = models.GetPortfolioInvitations(DB, portfolioID)
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Members: models.GetPortfolioMembers(DB, portfolio), Invitations: invitations, Roles: models.PortfolioRoles}, "success", 200)
+
+		return
+	}
+}
+
+// InvitePortfolioMember - invite user by email address to the portfolio
+func InvitePortfolioMember(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		portfolioID, err := strconv.Atoi(mux.Vars(r)["portfolioid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		// InvitationInformation - who to invite and with what role
+		type InvitationInformation struct {
+			EmailAddress string `json:"email_address"`
+			Role         string `json:"role"`
+		}
+
+		invitationInformation := &InvitationInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(invitationInformation)
+
+		if err != nil || invitationInformation.EmailAddress == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !helpers.ValidateEmailAddress(models.NormalizeEmailAddress(invitationInformation.EmailAddress)) {
+			response := "Please provide a valid email address."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidatePortfolioRole(invitationInformation.Role); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "owner")
+
+		if !ok {
+			return
+		}
+
+		if models.HasPortfolioAccess(DB, portfolio, invitationInformation.EmailAddress) {
+			response := "This user is already a member of this portfolio."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		invitationID := models.CreatePortfolioInvitation(DB, userID, portfolio, invitationInformation.EmailAddress, invitationInformation.Role)
+
+		if invitationID < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		helpers.Respond(w, r, models.GetPortfolioInvitation(DB, invitationID), "success", 200)
+
+		return
+	}
+}
+
+// DeletePortfolioInvitation - cancel pending invitation to the portfolio
+func DeletePortfolioInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		portfolioID, err := strconv.Atoi(vars["portfolioid"])
+		invitationID, invitationErr := strconv.Atoi(vars["invitationid"])
+
+		if err != nil || invitationErr != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if _, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "owner"); !ok {
+			return
+		}
+
+		if !models.RemovePortfolioInvitation(DB, portfolioID, invitationID) {
+			response := "This invitation does not exist or has been answered already."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Invitation has been cancelled successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// EditPortfolioMember - change role of a portfolio member
+func EditPortfolioMember(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		portfolioID, err := strconv.Atoi(vars["portfolioid"])
+		memberID, memberErr := strconv.Atoi(vars["userid"])
+
+		if err != nil || memberErr != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		// MemberInformation - new role of the member
+		type MemberInformation struct {
+			Role string `json:"role"`
+		}
+
+		memberInformation := &MemberInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(memberInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidatePortfolioRole(memberInformation.Role); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "owner")
+
+		if !ok {
+			return
+		}
+
+		if memberID == portfolio.OwnerID {
+			response := "The role of the user who created this portfolio can not be changed."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !models.IsPortfolioMember(DB, portfolioID, memberID) {
+			response := "This user is not a member of this portfolio."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		models.SetPortfolioMember(DB, portfolioID, memberID, memberInformation.Role)
+
+		response := "Member has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeletePortfolioMember - stop sharing the portfolio with a member, members can also remove themselves
+func DeletePortfolioMember(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		portfolioID, err := strconv.Atoi(vars["portfolioid"])
+		memberID, memberErr := strconv.Atoi(vars["userid"])
+
+		if err != nil || memberErr != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		requiredRole := "owner"
+
+		if memberID == userID {
+			requiredRole = "viewer"
+		}
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, requiredRole)
+
+		if !ok {
+			return
+		}
+
+		if memberID == portfolio.OwnerID {
+			response := "The user who created this portfolio can not be removed from it."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !models.RemovePortfolioMember(DB, portfolioID, memberID) {
+			response := "This user is not a member of this portfolio."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Member has been removed successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// GetInvitations - get pending portfolio invitations sent to user's email address
+func GetInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Invitations []models.PortfolioInvitation `json:"invitations"`
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Invitations: models.GetUserInvitations(DB, userID)}, "success", 200)
+
+		return
+	}
+}
+
+// RespondToInvitation - accept or decline portfolio invitation
+func RespondToInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+		action := vars["action"]
+
+		if action != "accept" && action != "decline" {
+			response := "Such action does not exists.."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		invitationID, err := strconv.Atoi(vars["invitationid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if !models.RespondToInvitation(DB, userID, invitationID, action == "accept") {
+			response := "This invitation does not exist or has been answered already."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Invitation has been " + action + "d successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// NotesInformation - markdown notes sent by the user, empty notes remove them
+type NotesInformation struct {
+	Notes string `json:"notes"`
+}
+
+// UpdateCoinNotes - replace notes of the coin
+func UpdateCoinNotes(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		coinid := mux.Vars(r)["coinid"]
+
+		notesInformation := &NotesInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(notesInformation)
+
+		if err != nil || coinid == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateNotes(notesInformation.Notes); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if _, ok := coinPortfolio(w, r, DB, userID, coinid, "editor"); !ok {
+			return
+		}
+
+		if models.SetHoldingNotes(DB, coinid, notesInformation.Notes) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Coin notes have been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// UpdateTransactionNotes - replace notes of the transaction
+func UpdateTransactionNotes(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		transactionID, err := strconv.Atoi(mux.Vars(r)["transactionid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		notesInformation := &NotesInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(notesInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateNotes(notesInformation.Notes); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		ownerID, portfolioID := models.GetTransactionPortfolio(DB, transactionID)
+
+		if _, ok := recordPortfolio(w, r, DB, userID, ownerID, portfolioID, "This transaction does not belong to you!", "editor"); !ok {
+			return
+		}
+
+		if models.SetTransactionNotes(DB, transactionID, notesInformation.Notes) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Transaction notes have been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetNotifications - get user's notification preferences and latest emails
+func GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Email       []string             `json:"email"`
+			EmailEvents []string             `json:"email_events"`
+			Emails      []models.QueuedEmail `json:"emails"`
+		}
+
+		emails := models.GetUserEmails(DB, userID, 50)
+
+		if emails == nil {
+			emails = []models.QueuedEmail{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{
+			Email:       models.GetEmailNotifications(DB, userID),
+			EmailEvents: models.EmailNotificationEvents,
+			Emails:      emails,
+		}, "success", 200)
+
+		return
+	}
+}
+
+// UpdateNotifications - update events the user receives by email
+func UpdateNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		// NotificationsInformation - notification preferences
+		type NotificationsInformation struct {
+			Email []string `json:"email"`
+		}
+
+		notificationsInformation := &NotificationsInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(notificationsInformation)
+
+		if err != nil || notificationsInformation.Email == nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateEmailNotifications(notificationsInformation.Email); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		models.SetEmailNotifications(DB, userID, notificationsInformation.Email)
+
+		response := "Notification preferences have been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// TestEmail - send test email right away and return the outcome
+func TestEmail(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		emailID := models.QueueTemplateEmail(DB, userID, "email.test", "test", nil)
+
+		if emailID < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		helpers.Respond(w, r, models.SendQueuedEmail(DB, emailID), "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetPerformance - get time-weighted, money-weighted and annualized returns of the portfolio and its coins
+func GetPerformance(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		period := r.URL.Query().Get("period")
+
+		if period == "" {
+			period = "all"
+		}
+
+		if err := models.ValidatePerformancePeriod(period); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.Performance
+		}
+
+		performance, currency := models.ConvertPerformance(DB, models.GetPerformance(DB, portfolio.OwnerID, portfolio.PortfolioID, period), portfolio.Currency)
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, Performance: performance}, "success", 200)
+
+		return
+	}
+}
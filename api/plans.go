@@ -0,0 +1,327 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// PlanInformation - recurring plan settings sent by the user
+type PlanInformation struct {
+	Symbol   string   `json:"symbol"`
+	Amount   *float64 `json:"amount"`
+	Fee      *float64 `json:"fee"`
+	Schedule string   `json:"schedule"`
+	Lives    string   `json:"lives"`
+	Mode     string   `json:"mode"`
+	Enabled  *bool    `json:"enabled"`
+	NextRun  string   `json:"next_run"`
+}
+
+// GetPlans - get recurring plans of the portfolio and the latest buys they generated
+func GetPlans(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Plans []models.Plan    `json:"plans"`
+			Buys  []models.PlanBuy `json:"buys"`
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{
+			Plans: models.GetPortfolioPlans(DB, portfolio.OwnerID, portfolio.PortfolioID),
+			Buys:  models.GetPortfolioPlanBuys(DB, portfolio.OwnerID, portfolio.PortfolioID, 50),
+		}, "success", 200)
+
+		return
+	}
+}
+
+// AddPlan - add recurring plan to the portfolio, first run is now unless next_run is given
+func AddPlan(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		planInformation := &PlanInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(planInformation)
+
+		if err != nil || planInformation.Symbol == "" || planInformation.Amount == nil || planInformation.Schedule == "" || planInformation.Lives == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		plan := models.Plan{
+			Symbol:    planInformation.Symbol,
+			AmountEur: *planInformation.Amount,
+			Schedule:  planInformation.Schedule,
+			Lives:     planInformation.Lives,
+			Mode:      planInformation.Mode,
+			Enabled:   planInformation.Enabled == nil || *planInformation.Enabled,
+			NextRun:   planInformation.NextRun,
+		}
+
+		if planInformation.Fee != nil {
+			plan.Fee = *planInformation.Fee
+		}
+
+		if plan.Mode == "" {
+			plan.Mode = "review"
+		}
+
+		if plan.NextRun == "" {
+			plan.NextRun = helpers.GetCurrentDateTime()
+		}
+
+		if err = models.ValidateNewPlan(plan); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, false, "editor")
+
+		if !ok {
+			return
+		}
+
+		plan.OwnerID = portfolio.OwnerID
+		plan.PortfolioID = portfolio.PortfolioID
+
+		plan.Name, _ = models.GetCoinBySymbol(DB, plan.Symbol)
+
+		if plan.Name == "" {
+			response := "This coin does not exist!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if models.CreatePlan(DB, plan) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Plan has been added successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// EditPlan - edit recurring plan, coin can not be changed
+func EditPlan(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		planID, err := strconv.Atoi(vars["planid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		planInformation := &PlanInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(planInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		plan := models.GetPlan(DB, planID)
+
+		if _, ok := recordPortfolio(w, r, DB, userID, plan.OwnerID, plan.PortfolioID, "This plan does not belong to you!", "editor"); !ok {
+			return
+		}
+
+		if planInformation.Amount != nil {
+			plan.AmountEur = *planInformation.Amount
+		}
+
+		if planInformation.Fee != nil {
+			plan.Fee = *planInformation.Fee
+		}
+
+		if planInformation.Schedule != "" {
+			plan.Schedule = planInformation.Schedule
+		}
+
+		if planInformation.Lives != "" {
+			plan.Lives = planInformation.Lives
+		}
+
+		if planInformation.Mode != "" {
+			plan.Mode = planInformation.Mode
+		}
+
+		if planInformation.Enabled != nil {
+			plan.Enabled = *planInformation.Enabled
+		}
+
+		if planInformation.NextRun != "" {
+			plan.NextRun = planInformation.NextRun
+		}
+
+		if err = models.ValidatePlan(plan); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		if models.UpdatePlan(DB, plan) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Plan has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeletePlan - delete recurring plan and its pending buys
+func DeletePlan(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		planID, err := strconv.Atoi(vars["planid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		plan := models.GetPlan(DB, planID)
+
+		if _, ok := recordPortfolio(w, r, DB, userID, plan.OwnerID, plan.PortfolioID, "This plan does not belong to you!", "editor"); !ok {
+			return
+		}
+
+		if !models.RemovePlan(DB, planID) {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Plan has been deleted successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// ReviewPlanBuy - approve pending buy into the ledger or reject it
+func ReviewPlanBuy(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+
+		planBuyID, err := strconv.Atoi(vars["planbuyid"])
+
+		if err != nil || (vars["action"] != "approve" && vars["action"] != "reject") {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		ownerID, portfolioID := models.GetPlanBuyPortfolio(DB, planBuyID)
+
+		if _, ok := recordPortfolio(w, r, DB, userID, ownerID, portfolioID, "This buy does not belong to you!", "editor"); !ok {
+			return
+		}
+
+		planBuy := models.GetPlanBuy(DB, planBuyID)
+
+		if planBuy.Status != "pending" {
+			response := "This buy has been reviewed already."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if vars["action"] == "reject" {
+			if models.RejectPlanBuy(DB, planBuyID) < 1 {
+				helpers.DefaultErrorRespond(w, r)
+
+				return
+			}
+
+			response := "Buy has been rejected successfully!"
+
+			helpers.Respond(w, r, response, "success", 200)
+
+			return
+		}
+
+		if models.ApprovePlanBuy(DB, ownerID, planBuy) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Buy has been added to the ledger successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
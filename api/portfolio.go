@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/karolispx/golang-crypto-portfolio/helpers"
@@ -11,23 +12,95 @@ import (
 )
 
 // GetProfits - get user profits
+// Coins can be filtered by location, tag and symbols (comma separated), sorted by worth, profit, change or name,
+// and coins worth less than min_worth hidden. Totals include hidden coins unless totals=visible.
+// group=tag adds the coins grouped by tag with subtotals.
 func GetProfits(w http.ResponseWriter, r *http.Request) {
 	userID := helpers.ValidateJWT(w, r)
 
 	if userID > 0 {
+		query := r.URL.Query()
+
+		if group := query.Get("group"); group != "" && group != "tag" {
+			response := "Coins can only be grouped by tag."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if totals := query.Get("totals"); totals != "" && totals != "all" && totals != "visible" {
+			response := "Totals have to be all or visible."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		options := models.ProfitsOptions{
+			Sort:                query.Get("sort"),
+			Order:               query.Get("order"),
+			Location:            query.Get("location"),
+			Tag:                 query.Get("tag"),
+			TotalsIncludeHidden: query.Get("totals") != "visible",
+		}
+
+		if symbols := query.Get("symbol"); symbols != "" {
+			options.Symbols = strings.Split(symbols, ",")
+		}
+
+		if minWorth := query.Get("min_worth"); minWorth != "" {
+			var err error
+
+			options.MinWorth, err = strconv.ParseFloat(minWorth, 64)
+
+			if err != nil {
+				response := "Please provide valid numbers."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+		}
+
+		if err := models.ValidateProfitsOptions(options); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
 		DB := helpers.InitDB()
 
 		defer DB.Close()
 
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
 		type ResponseSuccessData struct {
-			CoinData []models.Coin   `json:"coindata"`
-			SyncData models.SyncInfo `json:"syncdata"`
+			Portfolio models.Portfolio `json:"portfolio"`
+			Currency  string           `json:"currency"`
+			models.ProfitsView
+			Groups []models.CoinGroup `json:"groups,omitempty"`
 		}
 
 		// Get coins
-		processCoins, syncInfo := models.GetUserCoins(userID, DB)
+		processCoins, syncInfo := models.GetUserCoins(portfolio.OwnerID, portfolio.PortfolioID, DB)
+
+		processCoins, _, currency := models.ConvertCoins(DB, processCoins, syncInfo, portfolio.Currency)
 
-		helpers.Respond(w, r, ResponseSuccessData{CoinData: processCoins, SyncData: syncInfo}, "success", 200)
+		// Dust threshold is in the portfolio currency, so options are applied to converted coins
+		view := models.ApplyProfitsOptions(processCoins, options)
+
+		var groups []models.CoinGroup
+
+		if query.Get("group") == "tag" {
+			groups = models.GroupCoinsByTag(view.CoinData)
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Portfolio: portfolio, Currency: currency, ProfitsView: view, Groups: groups}, "success", 200)
 
 		return
 	}
@@ -52,13 +125,19 @@ func GetProfitsEndpoint(w http.ResponseWriter, r *http.Request) {
 	defer DB.Close()
 
 	type ResponseSuccessData struct {
+		Currency string          `json:"currency"`
 		CoinData []models.Coin   `json:"coindata"`
 		SyncData models.SyncInfo `json:"syncdata"`
 	}
 
-	userID := models.UserProfitsEndpoint(DB, endpoint)
+	// Portfolio endpoints show one portfolio, the account endpoint shows all of them combined
+	portfolio := models.PortfolioByEndpoint(DB, endpoint)
+
+	if portfolio.PortfolioID < 1 {
+		portfolio = models.AllPortfoliosView(models.UserProfitsEndpoint(DB, endpoint))
+	}
 
-	if userID < 1 {
+	if portfolio.OwnerID < 1 {
 		response := "This profit endpoint does not exist or has not been enabled."
 
 		helpers.Respond(w, r, response, "error", 422)
@@ -67,9 +146,11 @@ func GetProfitsEndpoint(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get coins
-	processCoins, syncInfo := models.GetUserCoins(userID, DB)
+	processCoins, syncInfo := models.GetUserCoins(portfolio.OwnerID, portfolio.PortfolioID, DB)
 
-	helpers.Respond(w, r, ResponseSuccessData{CoinData: processCoins, SyncData: syncInfo}, "success", 200)
+	processCoins, syncInfo, currency := models.ConvertCoins(DB, processCoins, syncInfo, portfolio.Currency)
+
+	helpers.Respond(w, r, ResponseSuccessData{Currency: currency, CoinData: processCoins, SyncData: syncInfo}, "success", 200)
 
 	return
 
@@ -103,6 +184,7 @@ func AddCoin(w http.ResponseWriter, r *http.Request) {
 			Invested string `json:"invested"`
 			Amount   string `json:"amount"`
 			Lives    string `json:"lives"`
+			Date     string `json:"date"`
 		}
 
 		coin := &CoinInformation{}
@@ -117,7 +199,8 @@ func AddCoin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if coin.Symbol == "" || coin.Invested == "" || coin.Amount == "" || coin.Lives == "" {
+		// Invested amount can be left out when the date of the purchase is known
+		if coin.Symbol == "" || (coin.Invested == "" && coin.Date == "") || coin.Amount == "" || coin.Lives == "" {
 			response := "Please provide all information.2"
 
 			helpers.Respond(w, r, response, "error", 422)
@@ -127,6 +210,14 @@ func AddCoin(w http.ResponseWriter, r *http.Request) {
 
 		DB := helpers.InitDB()
 
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, false, "editor")
+
+		if !ok {
+			return
+		}
+
 		AddCoinName, AddCoinPriceEUR := models.GetCoinBySymbol(DB, coin.Symbol)
 
 		// No coin name came from from DB - coin doesn't exist
@@ -138,19 +229,45 @@ func AddCoin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		convertCoinInvested, err := strconv.ParseFloat(coin.Invested, 64)
+		convertCoinAmount, err := strconv.ParseFloat(coin.Amount, 64)
 
 		if err != nil {
 			panic(err)
 		}
 
-		convertCoinAmount, err := strconv.ParseFloat(coin.Amount, 64)
+		var convertCoinInvested float64
 
-		if err != nil {
-			panic(err)
+		if coin.Invested != "" {
+			convertCoinInvested, err = strconv.ParseFloat(coin.Invested, 64)
+
+			if err != nil {
+				panic(err)
+			}
+		} else {
+			boughtAt, err := helpers.ParseDateTime(coin.Date)
+
+			if err != nil {
+				response := "Please provide a valid date."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			investedAtDate, ok := models.GetInvestedAtDate(DB, models.GetCoinIDBySymbol(DB, coin.Symbol), convertCoinAmount, boughtAt)
+
+			if !ok {
+				response := "There is no price history for this coin at this date, please provide invested amount."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			convertCoinInvested = investedAtDate
 		}
 
-		checkUserHasCoin := models.CheckUserHasCoin(DB, userID, AddCoinName, coin.Symbol)
+		checkUserHasCoin := models.CheckUserHasCoin(DB, portfolio.OwnerID, portfolio.PortfolioID, AddCoinName, coin.Symbol)
 
 		if checkUserHasCoin > 0 {
 			response := "This coin exists in your portfolio already!"
@@ -160,9 +277,7 @@ func AddCoin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		createCoin := models.CreateCoin(DB, userID, AddCoinName, coin.Symbol, convertCoinInvested, convertCoinAmount, AddCoinPriceEUR, coin.Lives)
-
-		defer DB.Close()
+		createCoin := models.CreateCoin(DB, portfolio.OwnerID, portfolio.PortfolioID, AddCoinName, coin.Symbol, convertCoinInvested, convertCoinAmount, AddCoinPriceEUR, coin.Lives)
 
 		if createCoin < 1 {
 			helpers.DefaultErrorRespond(w, r)
@@ -170,6 +285,8 @@ func AddCoin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		models.NotifyUser(DB, portfolio.OwnerID, "holding.changed", models.HoldingChangedEvent{Action: "added", CoinID: createCoin, Symbol: coin.Symbol, Count: 1})
+
 		response := "Coin has been added successfully!"
 
 		helpers.Respond(w, r, response, "success", 200)
@@ -223,14 +340,12 @@ func EditCoin(w http.ResponseWriter, r *http.Request) {
 
 		DB := helpers.InitDB()
 
-		// Check if this coin belongs to the user
-		checkCoinBelongsToUser := models.CheckCoinBelongsToUser(DB, coinid, userID)
-
-		if checkCoinBelongsToUser < 1 {
-			response := "This coin does not belong to you!"
+		defer DB.Close()
 
-			helpers.Respond(w, r, response, "error", 422)
+		// Check if this coin is in a portfolio the user can edit
+		portfolio, ok := coinPortfolio(w, r, DB, userID, coinid, "editor")
 
+		if !ok {
 			return
 		}
 
@@ -246,9 +361,7 @@ func EditCoin(w http.ResponseWriter, r *http.Request) {
 			panic(err)
 		}
 
-		updateCoin := models.UpdateCoin(DB, convertCoinInvested, convertCoinAmount, coin.Lives, coinid, userID)
-
-		defer DB.Close()
+		updateCoin := models.UpdateCoin(DB, convertCoinInvested, convertCoinAmount, coin.Lives, coinid, portfolio.OwnerID)
 
 		if updateCoin < 1 {
 			helpers.DefaultErrorRespond(w, r)
@@ -256,6 +369,8 @@ func EditCoin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		models.NotifyUser(DB, portfolio.OwnerID, "holding.changed", models.HoldingChangedEvent{Action: "updated", CoinID: updateCoin, Count: 1})
+
 		response := "Coin has been updated successfully!"
 
 		helpers.Respond(w, r, response, "success", 200)
@@ -282,27 +397,27 @@ func DeleteCoin(w http.ResponseWriter, r *http.Request) {
 
 		DB := helpers.InitDB()
 
-		// Check if this coin belongs to the user
-		checkCoinBelongsToUser := models.CheckCoinBelongsToUser(DB, coinid, userID)
-
-		if checkCoinBelongsToUser < 1 {
-			response := "This coin does not belong to you!"
+		defer DB.Close()
 
-			helpers.Respond(w, r, response, "error", 422)
+		// Check if this coin is in a portfolio the user can edit
+		portfolio, ok := coinPortfolio(w, r, DB, userID, coinid, "editor")
 
+		if !ok {
 			return
 		}
 
 		removeCoin := models.RemoveCoin(DB, coinid)
 
-		defer DB.Close()
-
 		if removeCoin == false {
 			helpers.DefaultErrorRespond(w, r)
 
 			return
 		}
 
+		removedCoinID, _ := strconv.Atoi(coinid)
+
+		models.NotifyUser(DB, portfolio.OwnerID, "holding.changed", models.HoldingChangedEvent{Action: "deleted", CoinID: removedCoinID, Count: 1})
+
 		response := "Coin has been deleted successfully!"
 
 		helpers.Respond(w, r, response, "success", 200)
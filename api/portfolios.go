@@ -0,0 +1,368 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// PortfolioInformation - portfolio settings sent by the user
+type PortfolioInformation struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+}
+
+// requestPortfolio - portfolio chosen with ?portfolio= query, user's default one when it is left out
+// "all" picks every portfolio the user owns combined where allowAll is set. Responds with an error and returns false when the portfolio can not be used
+// or the user's role in it is lower than the required one.
+func requestPortfolio(w http.ResponseWriter, r *http.Request, DB *sql.DB, userID int, allowAll bool, role string) (models.Portfolio, bool) {
+	query := r.URL.Query().Get("portfolio")
+
+	if query == "" {
+		portfolio := models.GetDefaultPortfolio(DB, userID)
+
+		if portfolio.PortfolioID < 1 {
+			response := "Please create a portfolio first."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return models.Portfolio{}, false
+		}
+
+		return portfolio, true
+	}
+
+	if query == "all" {
+		if !allowAll {
+			response := "Please choose a portfolio."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return models.Portfolio{}, false
+		}
+
+		return models.AllPortfoliosView(userID), true
+	}
+
+	portfolioID, err := strconv.Atoi(query)
+
+	if err != nil {
+		response := "Please provide a valid portfolio."
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return models.Portfolio{}, false
+	}
+
+	return checkPortfolioRole(w, r, DB, userID, portfolioID, role)
+}
+
+// checkPortfolioRole - portfolio the user owns or is a member of, responds with an error and returns false when the user's role is lower than the required one
+func checkPortfolioRole(w http.ResponseWriter, r *http.Request, DB *sql.DB, userID int, portfolioID int, role string) (models.Portfolio, bool) {
+	portfolio := models.GetMemberPortfolio(DB, userID, portfolioID)
+
+	if portfolio.PortfolioID < 1 {
+		response := "This portfolio does not belong to you!"
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return models.Portfolio{}, false
+	}
+
+	if !models.PortfolioRoleAllows(portfolio.Role, role) {
+		response := "You need to be " + role + " of this portfolio to do this."
+
+		helpers.Respond(w, r, response, "error", 403)
+
+		return models.Portfolio{}, false
+	}
+
+	return portfolio, true
+}
+
+// GetPortfolios - get user's portfolios followed by portfolios shared with the user
+func GetPortfolios(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Portfolios []models.Portfolio `json:"portfolios"`
+			Currencies []string           `json:"currencies"`
+		}
+
+		portfolios := append(models.GetUserPortfolios(DB, userID), models.GetSharedPortfolios(DB, userID)...)
+
+		helpers.Respond(w, r, ResponseSuccessData{Portfolios: portfolios, Currencies: models.FXCurrencies}, "success", 200)
+
+		return
+	}
+}
+
+// AddPortfolio - add new portfolio
+func AddPortfolio(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		portfolioInformation := &PortfolioInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(portfolioInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		portfolio := models.Portfolio{Name: portfolioInformation.Name, Currency: portfolioInformation.Currency}
+
+		if portfolio.Currency == "" {
+			portfolio.Currency = "EUR"
+		}
+
+		if err = models.ValidatePortfolio(portfolio); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolioID := models.CreatePortfolio(DB, userID, portfolio)
+
+		if portfolioID < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		helpers.Respond(w, r, models.GetUserPortfolio(DB, userID, portfolioID), "success", 200)
+
+		return
+	}
+}
+
+// EditPortfolio - edit portfolio name and base currency
+func EditPortfolio(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		portfolioID, err := strconv.Atoi(mux.Vars(r)["portfolioid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		portfolioInformation := &PortfolioInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(portfolioInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "owner")
+
+		if !ok {
+			return
+		}
+
+		if portfolioInformation.Name != "" {
+			portfolio.Name = portfolioInformation.Name
+		}
+
+		if portfolioInformation.Currency != "" {
+			portfolio.Currency = portfolioInformation.Currency
+		}
+
+		if err = models.ValidatePortfolio(portfolio); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		if models.UpdatePortfolio(DB, portfolio.OwnerID, portfolio) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Portfolio has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeletePortfolio - delete empty portfolio, the last portfolio can not be deleted
+func DeletePortfolio(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		portfolioID, err := strconv.Atoi(mux.Vars(r)["portfolioid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "owner")
+
+		if !ok {
+			return
+		}
+
+		if len(models.GetUserPortfolios(DB, portfolio.OwnerID)) < 2 {
+			response := "You can not delete your only portfolio."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !models.PortfolioIsEmpty(DB, portfolioID) {
+			response := "Please remove all coins and transactions from this portfolio first."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if !models.RemovePortfolio(DB, portfolio.OwnerID, portfolioID) {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Portfolio has been deleted successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// UpdatePortfolioEndpoint - enable, disable or regenerate portfolio's public profits endpoint
+func UpdatePortfolioEndpoint(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		vars := mux.Vars(r)
+		action := vars["action"]
+
+		if action != "enable" && action != "disable" && action != "refresh" {
+			response := "Such action does not exists.."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		portfolioID, err := strconv.Atoi(vars["portfolioid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := checkPortfolioRole(w, r, DB, userID, portfolioID, "owner")
+
+		if !ok {
+			return
+		}
+
+		if !models.PortfolioEndpointAction(DB, portfolio.OwnerID, portfolioID, action) {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		actionWord := "enabled"
+
+		if action == "disable" {
+			actionWord = "disabled"
+		} else if action == "refresh" {
+			actionWord = "generated"
+		}
+
+		response := "Portfolio endpoint has been " + actionWord + " successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// coinPortfolio - portfolio of the holding, responds with an error and returns false when the user's role in it is lower than the required one
+// Holding has to be in the portfolio chosen with ?portfolio= query when one is given.
+func coinPortfolio(w http.ResponseWriter, r *http.Request, DB *sql.DB, userID int, coinid string, role string) (models.Portfolio, bool) {
+	ownerID, portfolioID := models.GetCoinPortfolio(DB, coinid)
+
+	if ownerID < 1 || models.GetMemberPortfolio(DB, userID, portfolioID).PortfolioID < 1 {
+		response := "This coin does not belong to you!"
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return models.Portfolio{}, false
+	}
+
+	if query := r.URL.Query().Get("portfolio"); query != "" && query != strconv.Itoa(portfolioID) {
+		response := "This coin is not in this portfolio!"
+
+		helpers.Respond(w, r, response, "error", 422)
+
+		return models.Portfolio{}, false
+	}
+
+	return checkPortfolioRole(w, r, DB, userID, portfolioID, role)
+}
+
+// recordPortfolio - portfolio of a plan, plan buy or transaction, responds with the message when the user is not a member of it
+func recordPortfolio(w http.ResponseWriter, r *http.Request, DB *sql.DB, userID int, ownerID int, portfolioID int, message string, role string) (models.Portfolio, bool) {
+	if ownerID < 1 || models.GetMemberPortfolio(DB, userID, portfolioID).PortfolioID < 1 {
+		helpers.Respond(w, r, message, "error", 422)
+
+		return models.Portfolio{}, false
+	}
+
+	return checkPortfolioRole(w, r, DB, userID, portfolioID, role)
+}
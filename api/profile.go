@@ -51,6 +51,8 @@ func UpdateProfitsEndpoint(w http.ResponseWriter, r *http.Request) {
 
 		DB := helpers.InitDB()
 
+		defer DB.Close()
+
 		profitsEndpointAction := models.ProfitsEndpointAction(DB, userID, action)
 
 		if profitsEndpointAction == false {
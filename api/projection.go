@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetProjection - get Monte Carlo projection of portfolio value over the coming months
+// Optional contribution is added every month, in the portfolio currency. Same seed always gives the same result.
+func GetProjection(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		months, simulations, contribution, seed := 12, 1000, 0.0, int64(1)
+
+		var err error
+
+		query := r.URL.Query()
+
+		if value := query.Get("months"); value != "" && err == nil {
+			months, err = strconv.Atoi(value)
+		}
+
+		if value := query.Get("simulations"); value != "" && err == nil {
+			simulations, err = strconv.Atoi(value)
+		}
+
+		if value := query.Get("contribution"); value != "" && err == nil {
+			contribution, err = strconv.ParseFloat(value, 64)
+		}
+
+		if value := query.Get("seed"); value != "" && err == nil {
+			seed, err = strconv.ParseInt(value, 10, 64)
+		}
+
+		if err != nil {
+			response := "Please provide valid numbers."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateProjection(months, simulations, contribution); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.Projection
+		}
+
+		coins, syncInfo := models.GetUserCoins(portfolio.OwnerID, portfolio.PortfolioID, DB)
+
+		coins, _, currency := models.ConvertCoins(DB, coins, syncInfo, portfolio.Currency)
+
+		projection, err := models.ProjectPortfolio(DB, coins, months, simulations, contribution, seed)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, Projection: projection}, "success", 200)
+
+		return
+	}
+}
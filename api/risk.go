@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetRisk - get volatility, maximum drawdown, Sharpe and Sortino ratios and correlation between held coins
+// Optional risk_free is the yearly risk-free rate in percent used by Sharpe and Sortino ratios.
+func GetRisk(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		period := r.URL.Query().Get("period")
+
+		if period == "" {
+			period = "1Y"
+		}
+
+		if err := models.ValidatePerformancePeriod(period); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		riskFreeRate := 0.0
+
+		if value := r.URL.Query().Get("risk_free"); value != "" {
+			var err error
+
+			riskFreeRate, err = strconv.ParseFloat(value, 64)
+
+			if err != nil {
+				response := "Please provide valid numbers."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.Risk
+		}
+
+		// Risk metrics are percentages and ratios, one rate moves every value by the same factor so only the currency is reported
+		_, currency := models.ConversionRate(DB, portfolio.Currency)
+
+		risk := models.GetRisk(DB, portfolio.OwnerID, portfolio.PortfolioID, period, riskFreeRate)
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, Risk: risk}, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// RunScenario - get holdings and totals recalculated with hypothetical prices, nothing is saved
+// Hypothetical prices are in EUR (priceeur) or in the portfolio currency (price), the result is in the portfolio currency.
+func RunScenario(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		scenario := models.Scenario{}
+
+		err := json.NewDecoder(r.Body).Decode(&scenario)
+
+		if err != nil || len(scenario.Shocks) == 0 {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		if err = models.ValidateScenario(DB, scenario); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Currency string `json:"currency"`
+			models.ScenarioResult
+		}
+
+		scenario = models.ScenarioPricesToEUR(DB, scenario, portfolio.Currency)
+
+		coins, _ := models.GetUserCoins(portfolio.OwnerID, portfolio.PortfolioID, DB)
+
+		result, currency := models.ConvertScenarioResult(DB, models.RunScenario(DB, coins, scenario), portfolio.Currency)
+
+		helpers.Respond(w, r, ResponseSuccessData{Currency: currency, ScenarioResult: result}, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetTags - get tags the user has created
+func GetTags(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Tags []string `json:"tags"`
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Tags: models.GetUserTags(DB, userID)}, "success", 200)
+
+		return
+	}
+}
+
+// UpdateCoinTags - replace tags of the coin
+func UpdateCoinTags(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		coinid := mux.Vars(r)["coinid"]
+
+		// TagsInformation - tags sent by the user
+		type TagsInformation struct {
+			Tags []string `json:"tags"`
+		}
+
+		tagsInformation := &TagsInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(tagsInformation)
+
+		if err != nil || coinid == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		tags, err := models.NormalizeTags(tagsInformation.Tags)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := coinPortfolio(w, r, DB, userID, coinid, "editor")
+
+		if !ok {
+			return
+		}
+
+		userCoinID, _ := strconv.Atoi(coinid)
+
+		models.SetHoldingTags(DB, portfolio.OwnerID, userCoinID, tags)
+
+		response := "Coin tags have been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// UpdateTransactionTags - replace tags of the transaction
+func UpdateTransactionTags(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		transactionID, err := strconv.Atoi(mux.Vars(r)["transactionid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		// TagsInformation - tags sent by the user
+		type TagsInformation struct {
+			Tags []string `json:"tags"`
+		}
+
+		tagsInformation := &TagsInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(tagsInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		tags, err := models.NormalizeTags(tagsInformation.Tags)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		ownerID, portfolioID := models.GetTransactionPortfolio(DB, transactionID)
+
+		if _, ok := recordPortfolio(w, r, DB, userID, ownerID, portfolioID, "This transaction does not belong to you!", "editor"); !ok {
+			return
+		}
+
+		models.SetTransactionTags(DB, ownerID, transactionID, tags)
+
+		response := "Transaction tags have been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// UpdateCoinTargets - set take-profit and stop-loss prices of the coin in EUR, 0 removes a target
+// With alerts set, price alerts are added for the targets as well.
+func UpdateCoinTargets(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		coinid := mux.Vars(r)["coinid"]
+
+		// TargetsInformation - target prices sent by the user
+		type TargetsInformation struct {
+			TakeProfit float64 `json:"take_profit"`
+			StopLoss   float64 `json:"stop_loss"`
+			Alerts     bool    `json:"alerts"`
+		}
+
+		targetsInformation := &TargetsInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(targetsInformation)
+
+		if err != nil || coinid == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateHoldingTargets(targetsInformation.TakeProfit, targetsInformation.StopLoss); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := coinPortfolio(w, r, DB, userID, coinid, "editor")
+
+		if !ok {
+			return
+		}
+
+		if models.SetHoldingTargets(DB, coinid, targetsInformation.TakeProfit, targetsInformation.StopLoss) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Coin targets have been updated successfully!"
+
+		if models.UpdateTargetAlerts(DB, portfolio.OwnerID, coinid, targetsInformation.TakeProfit, targetsInformation.StopLoss, targetsInformation.Alerts) > 0 {
+			response = "Coin targets and their alerts have been updated successfully!"
+		}
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
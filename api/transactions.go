@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// GetTransactions - get user's transaction ledger
+func GetTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Transactions []models.Transaction `json:"transactions"`
+		}
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, true, "viewer")
+
+		if !ok {
+			return
+		}
+
+		transactions := models.GetUserTransactions(DB, portfolio.OwnerID, portfolio.PortfolioID)
+
+		if transactions == nil {
+			transactions = []models.Transaction{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Transactions: transactions}, "success", 200)
+
+		return
+	}
+}
+
+// AddTransaction - add buy or sell to the ledger and update the holding
+// When neither price nor total is given, price is taken from price history at the transaction date.
+func AddTransaction(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		// TransactionInformation - transaction information
+		type TransactionInformation struct {
+			Type   string   `json:"type"`
+			Symbol string   `json:"symbol"`
+			Amount string   `json:"amount"`
+			Price  string   `json:"price"`
+			Total  string   `json:"total"`
+			Fee    string   `json:"fee"`
+			Lives  string   `json:"lives"`
+			Date   string   `json:"date"`
+			Notes  string   `json:"notes"`
+			Tags   []string `json:"tags"`
+		}
+
+		transactionInformation := &TransactionInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(transactionInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if transactionInformation.Symbol == "" || transactionInformation.Amount == "" || transactionInformation.Date == "" || transactionInformation.Lives == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if transactionInformation.Type != "buy" && transactionInformation.Type != "sell" {
+			response := "Transaction type has to be buy or sell."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if err = models.ValidateNotes(transactionInformation.Notes); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		tags, err := models.NormalizeTags(transactionInformation.Tags)
+
+		if err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		transactionDate, err := helpers.ParseDateTime(transactionInformation.Date)
+
+		if err != nil {
+			response := "Please provide a valid date."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		transaction := models.Transaction{
+			Type:            transactionInformation.Type,
+			Symbol:          transactionInformation.Symbol,
+			Lives:           transactionInformation.Lives,
+			Source:          "manual",
+			Notes:           transactionInformation.Notes,
+			Tags:            tags,
+			DateTransaction: helpers.FormatDateTime(transactionDate),
+		}
+
+		for _, field := range []struct {
+			Value  string
+			Target *float64
+		}{
+			{transactionInformation.Amount, &transaction.Amount},
+			{transactionInformation.Price, &transaction.PriceEur},
+			{transactionInformation.Total, &transaction.Total},
+			{transactionInformation.Fee, &transaction.Fee},
+		} {
+			if field.Value == "" {
+				continue
+			}
+
+			*field.Target, err = strconv.ParseFloat(field.Value, 64)
+
+			if err != nil || *field.Target < 0 {
+				response := "Please provide valid numbers."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+		}
+
+		if transaction.Amount <= 0 {
+			response := "Amount has to be greater than 0."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		portfolio, ok := requestPortfolio(w, r, DB, userID, false, "editor")
+
+		if !ok {
+			return
+		}
+
+		transaction.PortfolioID = portfolio.PortfolioID
+
+		transaction.Name, _ = models.GetCoinBySymbol(DB, transaction.Symbol)
+
+		if transaction.Name == "" {
+			response := "This coin does not exist!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if transaction.Total == 0 && transaction.PriceEur == 0 {
+			priceAtDate, ok := models.GetCoinPriceAt(DB, models.GetCoinIDBySymbol(DB, transaction.Symbol), transactionDate)
+
+			if !ok {
+				response := "There is no price history for this coin at this date, please provide price or total."
+
+				helpers.Respond(w, r, response, "error", 422)
+
+				return
+			}
+
+			transaction.PriceEur = priceAtDate
+		}
+
+		if transaction.Total == 0 {
+			transaction.Total = transaction.PriceEur * transaction.Amount
+		} else if transaction.PriceEur == 0 {
+			transaction.PriceEur = transaction.Total / transaction.Amount
+		}
+
+		transaction.Hash = models.NewTransactionHash(transaction)
+
+		transactionID := models.ApplyTransaction(DB, portfolio.OwnerID, transaction)
+
+		if transactionID < 1 {
+			response := "There is not enough " + transaction.Symbol + " in your portfolio to sell."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		models.NotifyUser(DB, portfolio.OwnerID, "holding.changed", models.HoldingChangedEvent{Action: transaction.Type, Symbol: transaction.Symbol, Count: 1})
+
+		response := "Transaction has been added successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
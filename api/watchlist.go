@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// WatchlistInformation - watchlist entry sent by the user
+type WatchlistInformation struct {
+	Symbol string `json:"symbol"`
+	Notes  string `json:"notes"`
+}
+
+// GetWatchlist - get user's watchlist with prices and 24h change
+func GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Watchlist []models.WatchlistEntry `json:"watchlist"`
+		}
+
+		watchlist := models.GetUserWatchlist(DB, userID)
+
+		if watchlist == nil {
+			watchlist = []models.WatchlistEntry{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Watchlist: watchlist}, "success", 200)
+
+		return
+	}
+}
+
+// AddToWatchlist - add coin to the watchlist
+func AddToWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		watchlistInformation := &WatchlistInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(watchlistInformation)
+
+		if err != nil || watchlistInformation.Symbol == "" {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		coinID := models.GetCoinIDBySymbol(DB, watchlistInformation.Symbol)
+
+		if coinID < 1 {
+			response := "This coin does not exist!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if models.IsCoinWatched(DB, userID, coinID) {
+			response := "This coin is already on your watchlist!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if models.AddToWatchlist(DB, userID, coinID, watchlistInformation.Notes) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Coin has been added to your watchlist successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// EditWatchlist - edit notes of watchlist entry
+func EditWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		watchlistID, err := strconv.Atoi(mux.Vars(r)["watchlistid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		watchlistInformation := &WatchlistInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(watchlistInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if models.UpdateWatchlistNotes(DB, userID, watchlistID, watchlistInformation.Notes) < 1 {
+			response := "This watchlist entry does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Watchlist entry has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeleteFromWatchlist - remove coin from the watchlist
+func DeleteFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		watchlistID, err := strconv.Atoi(mux.Vars(r)["watchlistid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if !models.RemoveFromWatchlist(DB, userID, watchlistID) {
+			response := "This watchlist entry does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Coin has been removed from your watchlist successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	"github.com/karolispx/golang-crypto-portfolio/models"
+)
+
+// WebhookInformation - webhook settings sent by the user
+type WebhookInformation struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// GetWebhooks - get user's webhooks
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		type ResponseSuccessData struct {
+			Webhooks []models.Webhook `json:"webhooks"`
+			Events   []string         `json:"events"`
+		}
+
+		webhooks := models.GetUserWebhooks(DB, userID)
+
+		if webhooks == nil {
+			webhooks = []models.Webhook{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Webhooks: webhooks, Events: models.WebhookEvents}, "success", 200)
+
+		return
+	}
+}
+
+// AddWebhook - register new webhook, response contains the secret used to sign payloads
+func AddWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		webhookInformation := &WebhookInformation{}
+
+		err := json.NewDecoder(r.Body).Decode(webhookInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		webhook := models.Webhook{
+			URL:     webhookInformation.URL,
+			Events:  webhookInformation.Events,
+			Enabled: webhookInformation.Enabled == nil || *webhookInformation.Enabled,
+			Secret:  models.GenerateWebhookSecret(),
+		}
+
+		if err = models.ValidateWebhook(webhook); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		webhook.WebhookID = models.CreateWebhook(DB, userID, webhook)
+
+		if webhook.WebhookID < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		helpers.Respond(w, r, webhook, "success", 200)
+
+		return
+	}
+}
+
+// EditWebhook - edit webhook
+func EditWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		webhookID, err := strconv.Atoi(mux.Vars(r)["webhookid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		webhookInformation := &WebhookInformation{}
+
+		err = json.NewDecoder(r.Body).Decode(webhookInformation)
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		webhook := models.GetUserWebhook(DB, userID, webhookID)
+
+		if webhook.WebhookID < 1 {
+			response := "This webhook does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		if webhookInformation.URL != "" {
+			webhook.URL = webhookInformation.URL
+		}
+
+		if webhookInformation.Events != nil {
+			webhook.Events = webhookInformation.Events
+		}
+
+		if webhookInformation.Enabled != nil {
+			webhook.Enabled = *webhookInformation.Enabled
+		}
+
+		if err = models.ValidateWebhook(webhook); err != nil {
+			helpers.Respond(w, r, err.Error(), "error", 422)
+
+			return
+		}
+
+		if models.UpdateWebhook(DB, userID, webhook) < 1 {
+			helpers.DefaultErrorRespond(w, r)
+
+			return
+		}
+
+		response := "Webhook has been updated successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// DeleteWebhook - delete webhook
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		webhookID, err := strconv.Atoi(mux.Vars(r)["webhookid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if !models.RemoveWebhook(DB, userID, webhookID) {
+			response := "This webhook does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		response := "Webhook has been deleted successfully!"
+
+		helpers.Respond(w, r, response, "success", 200)
+
+		return
+	}
+}
+
+// GetWebhookDeliveries - get delivery log of the webhook
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		webhookID, err := strconv.Atoi(mux.Vars(r)["webhookid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		if models.GetUserWebhook(DB, userID, webhookID).WebhookID < 1 {
+			response := "This webhook does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		type ResponseSuccessData struct {
+			Deliveries []models.WebhookDelivery `json:"deliveries"`
+		}
+
+		deliveries := models.GetWebhookDeliveries(DB, webhookID, 100)
+
+		if deliveries == nil {
+			deliveries = []models.WebhookDelivery{}
+		}
+
+		helpers.Respond(w, r, ResponseSuccessData{Deliveries: deliveries}, "success", 200)
+
+		return
+	}
+}
+
+// TestWebhook - send test event to the webhook right away and return the delivery
+func TestWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := helpers.ValidateJWT(w, r)
+
+	if userID > 0 {
+		webhookID, err := strconv.Atoi(mux.Vars(r)["webhookid"])
+
+		if err != nil {
+			response := "Please provide all information."
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		DB := helpers.InitDB()
+
+		defer DB.Close()
+
+		webhook := models.GetUserWebhook(DB, userID, webhookID)
+
+		if webhook.WebhookID < 1 {
+			response := "This webhook does not belong to you!"
+
+			helpers.Respond(w, r, response, "error", 422)
+
+			return
+		}
+
+		type TestEvent struct {
+			Message string `json:"message"`
+		}
+
+		delivery := models.SendWebhookTest(DB, userID, webhook.WebhookID, TestEvent{Message: "This is a test event."})
+
+		helpers.Respond(w, r, delivery, "success", 200)
+
+		return
+	}
+}
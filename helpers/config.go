@@ -20,7 +20,14 @@ type Configuration struct {
 	RestAPIPath   string `json:"RestAPIPath"`
 	RestAPIURL    string `json:"RestAPIURL"`
 	APPName       string `json:"APPName"`
-	CMCAPIKeys    []struct {
+	SMTPHost      string `json:"SMTPHost"`
+	SMTPPort      string `json:"SMTPPort"`
+	SMTPUser      string `json:"SMTPUser"`
+	SMTPPassword  string `json:"SMTPPassword"`
+	SMTPFrom      string `json:"SMTPFrom"`
+	// AllowPrivateOutbound - let webhooks and channels reach local and private addresses, for local development only
+	AllowPrivateOutbound bool `json:"AllowPrivateOutbound"`
+	CMCAPIKeys           []struct {
 		APIKey string `json:"APIKey"`
 	} `json:"CMCApiKeys"`
 }
@@ -0,0 +1,146 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ExportTable - tabular data for CSV and XLSX exports
+type ExportTable struct {
+	Headers []string
+	Rows    [][]string
+}
+
+// RespondFile - send data as a file download
+func RespondFile(w http.ResponseWriter, r *http.Request, fileName string, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+fileName+"\"")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+
+	w.WriteHeader(200)
+
+	w.Write(data)
+}
+
+// FormatNumber - format float for exports without losing precision
+func FormatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+// WriteCSV - write table as CSV
+func WriteCSV(table ExportTable) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(table.Headers); err != nil {
+		return nil, err
+	}
+
+	if err := writer.WriteAll(table.Rows); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// xlsxFiles - static parts of a single sheet XLSX workbook
+var xlsxFiles = []struct {
+	Name    string
+	Content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// WriteXLSX - write table as a single sheet XLSX workbook, numbers are stored as numeric cells
+func WriteXLSX(sheetName string, table ExportTable) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	archive := zip.NewWriter(&buffer)
+
+	for _, file := range xlsxFiles {
+		writer, err := archive.Create(file.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = writer.Write([]byte(file.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	workbook, err := archive.Create("xl/workbook.xml")
+
+	if err != nil {
+		return nil, err
+	}
+
+	workbook.Write([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="`))
+	xml.EscapeText(workbook, []byte(sheetName))
+	workbook.Write([]byte(`" sheetId="1" r:id="rId1"/></sheets></workbook>`))
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+
+	if err != nil {
+		return nil, err
+	}
+
+	sheet.Write([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`))
+
+	rows := append([][]string{table.Headers}, table.Rows...)
+
+	for rowIndex, row := range rows {
+		rowNumber := strconv.Itoa(rowIndex + 1)
+
+		sheet.Write([]byte(`<row r="` + rowNumber + `">`))
+
+		for columnIndex, value := range row {
+			cell := xlsxColumnName(columnIndex) + rowNumber
+
+			if number, err := strconv.ParseFloat(value, 64); err == nil && rowIndex > 0 && !math.IsInf(number, 0) && !math.IsNaN(number) {
+				sheet.Write([]byte(`<c r="` + cell + `"><v>` + value + `</v></c>`))
+
+				continue
+			}
+
+			sheet.Write([]byte(`<c r="` + cell + `" t="inlineStr"><is><t>`))
+			xml.EscapeText(sheet, []byte(value))
+			sheet.Write([]byte(`</t></is></c>`))
+		}
+
+		sheet.Write([]byte(`</row>`))
+	}
+
+	sheet.Write([]byte(`</sheetData></worksheet>`))
+
+	if err = archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// xlsxColumnName - spreadsheet column name for zero based index (0 = A, 26 = AA)
+func xlsxColumnName(index int) string {
+	name := ""
+
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,11 +24,64 @@ type Token struct {
 	jwt.StandardClaims
 }
 
+// DateTimeFormat - format of every date stored in the DB
+const DateTimeFormat = "2006.01.02 15:04:05"
+
+// dateTimeLayouts - layouts accepted when parsing dates coming from users or CSV files
+// Dates with slashes are left out, 01/02/2006 is the 2nd of January in some exports and the 1st of February in others.
+var dateTimeLayouts = []string{
+	DateTimeFormat,
+	time.RFC3339,
+	"2006-01-02T15:04:05.000Z",
+	"2006-01-02 15:04:05.0000",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Jan. 02, 2006, 03:04 PM",
+	"Jan 02, 2006, 03:04 PM",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
 // GetCurrentDateTime in string format
 func GetCurrentDateTime() string {
 	currentTime := time.Now()
 
-	return currentTime.Format("2006.01.02 15:04:05")
+	return currentTime.Format(DateTimeFormat)
+}
+
+// FormatDateTime - format time the same way dates are stored in DB, in server's local time
+func FormatDateTime(t time.Time) string {
+	return t.In(time.Local).Format(DateTimeFormat)
+}
+
+// ParseDateTime - parse date in one of the known formats or unix timestamp, dates without a zone are in server's local time
+func ParseDateTime(value string) (time.Time, error) {
+	return ParseDateTimeIn(value, time.Local)
+}
+
+// ParseDateTimeIn - parse date like ParseDateTime, dates without a zone are in the location
+func ParseDateTimeIn(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+
+	if unix, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(int64(unix), 0), nil
+	}
+
+	for _, layout := range dateTimeLayouts {
+		parsed, err := time.ParseInLocation(layout, value, location)
+
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("date %q is not in a supported format", value)
 }
 
 // Respond - process rest api response
@@ -0,0 +1,225 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	htmltemplate "html/template"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EmailTemplatesPath - folder with email templates, every template has .txt and .html version
+const EmailTemplatesPath = "templates/email"
+
+// SMTPTimeout - longest time connecting to the SMTP server and sending one email can take
+const SMTPTimeout = 30 * time.Second
+
+// Email - rendered email ready to be sent
+type Email struct {
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	BodyText string `json:"body_text"`
+	BodyHTML string `json:"body_html"`
+}
+
+// EmailTemplateExists - check if both versions of the template exist
+func EmailTemplateExists(name string) bool {
+	for _, extension := range []string{".txt", ".html"} {
+		if _, err := os.Stat(filepath.Join(EmailTemplatesPath, name+extension)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RenderEmail - render text and HTML version of the template
+// Subject is the first line of the text template, the rest is the body.
+func RenderEmail(name string, data interface{}) (Email, error) {
+	var email Email
+
+	textTemplate, err := template.ParseFiles(filepath.Join(EmailTemplatesPath, name+".txt"))
+
+	if err != nil {
+		return email, err
+	}
+
+	htmlTemplate, err := htmltemplate.ParseFiles(filepath.Join(EmailTemplatesPath, name+".html"))
+
+	if err != nil {
+		return email, err
+	}
+
+	var text bytes.Buffer
+	var html bytes.Buffer
+
+	if err = textTemplate.Execute(&text, data); err != nil {
+		return email, err
+	}
+
+	if err = htmlTemplate.Execute(&html, data); err != nil {
+		return email, err
+	}
+
+	lines := strings.SplitN(text.String(), "\n", 2)
+
+	email.Subject = strings.TrimSpace(strings.TrimPrefix(lines[0], "Subject:"))
+
+	if len(lines) > 1 {
+		email.BodyText = strings.TrimLeft(lines[1], "\r\n")
+	}
+
+	email.BodyHTML = html.String()
+
+	return email, nil
+}
+
+// SendEmail - send email as multipart/alternative through the SMTP server from config.json
+// Authentication is skipped when SMTPUser is empty, e.g. for a local SMTP stand-in.
+func SendEmail(email Email) error {
+	Config := GetConfig()
+
+	from, err := mail.ParseAddress(Config.SMTPFrom)
+
+	if err != nil {
+		return err
+	}
+
+	to, err := mail.ParseAddress(email.To)
+
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+
+	if Config.SMTPUser != "" {
+		auth = smtp.PlainAuth("", Config.SMTPUser, Config.SMTPPassword, Config.SMTPHost)
+	}
+
+	message, err := buildEmailMessage(from, to, email)
+
+	if err != nil {
+		return err
+	}
+
+	return sendSMTP(Config.SMTPHost, Config.SMTPPort, auth, from.Address, to.Address, message)
+}
+
+// sendSMTP - deliver the message like smtp.SendMail, but give up when the server does not answer within SMTPTimeout
+// STARTTLS is used when the server supports it.
+func sendSMTP(host string, port string, auth smtp.Auth, from string, to string, message []byte) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), SMTPTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(SMTPTimeout)); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if auth != nil {
+		if err = client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// buildEmailMessage - headers and quoted-printable text and HTML parts
+func buildEmailMessage(from *mail.Address, to *mail.Address, email Email) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, err
+	}
+
+	boundary := hex.EncodeToString(boundaryBytes)
+
+	var message bytes.Buffer
+
+	message.WriteString("From: " + from.String() + "\r\n")
+	message.WriteString("To: " + to.String() + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", email.Subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: multipart/alternative; boundary=\"" + boundary + "\"\r\n\r\n")
+
+	for _, part := range []struct {
+		ContentType string
+		Body        string
+	}{
+		{"text/plain", email.BodyText},
+		{"text/html", email.BodyHTML},
+	} {
+		message.WriteString("--" + boundary + "\r\n")
+		message.WriteString("Content-Type: " + part.ContentType + "; charset=UTF-8\r\n")
+		message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		writer := quotedprintable.NewWriter(&message)
+
+		if _, err := writer.Write([]byte(part.Body)); err != nil {
+			return nil, err
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		message.WriteString("\r\n")
+	}
+
+	message.WriteString("--" + boundary + "--\r\n")
+
+	return message.Bytes(), nil
+}
@@ -18,12 +18,36 @@ func main() {
 
 	defer DB.Close()
 
+	// Give users registered before portfolios existed their default portfolio
+	models.MigrateDefaultPortfolios(DB)
+
 	// Update all coin info from CMC
 	go models.UpdateCoinsFromCMC(10, DB)
 
+	// Refresh prices of held and watched coins between full updates
+	go models.UpdateTrackedCoinsFromCMC(2, DB)
+
 	// Update all user's coins
 	go models.UpdateUsersCoins(5, DB)
 
+	// Send scheduled portfolio digests
+	go models.SendDigests(5, DB)
+
+	// Send queued webhook deliveries
+	go models.DeliverWebhooks(1, DB)
+
+	// Send queued emails
+	go models.SendQueuedEmails(1, DB)
+
+	// Post queued chat channel messages
+	go models.SendChannelMessages(1, DB)
+
+	// Update FX rates every 12 hours
+	go models.UpdateFXRates(12, DB)
+
+	// Run recurring buy plans that are due
+	go models.RunPlans(5, DB)
+
 	// Initialize routes
 	InitRoutes()
 }
@@ -38,16 +62,95 @@ func InitRoutes() {
 	router.HandleFunc(Config.RestAPIPath+"/auth/login", api.Login).Methods("POST")
 
 	router.HandleFunc(Config.RestAPIPath+"/profile", api.GetProfile).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/profile/notifications", api.GetNotifications).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/profile/notifications", api.UpdateNotifications).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/profile/notifications/test", api.TestEmail).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/profile/digest", api.GetDigest).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/profile/digest", api.UpdateDigest).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/profile/digest/preview", api.PreviewDigest).Methods("GET")
+
+	router.HandleFunc(Config.RestAPIPath+"/account/backup", api.GetBackup).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/account/restore", api.RestoreBackup).Methods("POST")
 
 	router.HandleFunc(Config.RestAPIPath+"/endpoint/profits/{endpoint}", api.GetProfitsEndpoint).Methods("GET")
 	router.HandleFunc(Config.RestAPIPath+"/endpoint/profits/{action}", api.UpdateProfitsEndpoint).Methods("PUT")
 
+	router.HandleFunc(Config.RestAPIPath+"/portfolios", api.GetPortfolios).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios", api.AddPortfolio).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}", api.EditPortfolio).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}", api.DeletePortfolio).Methods("DELETE")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}/endpoint/{action}", api.UpdatePortfolioEndpoint).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}/members", api.GetPortfolioMembers).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}/members/{userid}", api.EditPortfolioMember).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}/members/{userid}", api.DeletePortfolioMember).Methods("DELETE")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}/invitations", api.InvitePortfolioMember).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/portfolios/{portfolioid}/invitations/{invitationid}", api.DeletePortfolioInvitation).Methods("DELETE")
+
+	router.HandleFunc(Config.RestAPIPath+"/invitations", api.GetInvitations).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/invitations/{invitationid}/{action}", api.RespondToInvitation).Methods("PUT")
+
 	router.HandleFunc(Config.RestAPIPath+"/portfolio/profits", api.GetProfits).Methods("GET")
 	router.HandleFunc(Config.RestAPIPath+"/portfolio/symbols", api.GetSymbols).Methods("GET")
 
+	router.HandleFunc(Config.RestAPIPath+"/coins/{coinid}/price", api.GetCoinPrice).Methods("GET")
+
 	router.HandleFunc(Config.RestAPIPath+"/portfolio/coins", api.AddCoin).Methods("POST")
 	router.HandleFunc(Config.RestAPIPath+"/portfolio/coins/{coinid}", api.EditCoin).Methods("PUT")
 	router.HandleFunc(Config.RestAPIPath+"/portfolio/coins/{coinid}", api.DeleteCoin).Methods("DELETE")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/coins/{coinid}/tags", api.UpdateCoinTags).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/coins/{coinid}/targets", api.UpdateCoinTargets).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/coins/{coinid}/notes", api.UpdateCoinNotes).Methods("PUT")
+
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/allocation", api.GetAllocation).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/allocation/targets", api.UpdateAllocationTargets).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/rebalance", api.GetRebalance).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/performance", api.GetPerformance).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/benchmark", api.GetBenchmark).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/risk", api.GetRisk).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/scenario", api.RunScenario).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/projection", api.GetProjection).Methods("GET")
+
+	router.HandleFunc(Config.RestAPIPath+"/tags", api.GetTags).Methods("GET")
+
+	router.HandleFunc(Config.RestAPIPath+"/backtest", api.RunBacktest).Methods("POST")
+
+	router.HandleFunc(Config.RestAPIPath+"/watchlist", api.GetWatchlist).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/watchlist", api.AddToWatchlist).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/watchlist/{watchlistid}", api.EditWatchlist).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/watchlist/{watchlistid}", api.DeleteFromWatchlist).Methods("DELETE")
+
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/transactions", api.GetTransactions).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/transactions", api.AddTransaction).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/transactions/{transactionid}/tags", api.UpdateTransactionTags).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/transactions/{transactionid}/notes", api.UpdateTransactionNotes).Methods("PUT")
+
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/plans", api.GetPlans).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/plans", api.AddPlan).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/plans/{planid}", api.EditPlan).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/plans/{planid}", api.DeletePlan).Methods("DELETE")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/planbuys/{planbuyid}/{action}", api.ReviewPlanBuy).Methods("PUT")
+
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/import", api.ImportTransactions).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/portfolio/export/{dataset}", api.Export).Methods("GET")
+
+	router.HandleFunc(Config.RestAPIPath+"/alerts", api.GetAlerts).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/alerts", api.AddAlert).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/alerts/{alertid}", api.EditAlert).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/alerts/{alertid}", api.DeleteAlert).Methods("DELETE")
+
+	router.HandleFunc(Config.RestAPIPath+"/webhooks", api.GetWebhooks).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/webhooks", api.AddWebhook).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/webhooks/{webhookid}", api.EditWebhook).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/webhooks/{webhookid}", api.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc(Config.RestAPIPath+"/webhooks/{webhookid}/deliveries", api.GetWebhookDeliveries).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/webhooks/{webhookid}/test", api.TestWebhook).Methods("POST")
+
+	router.HandleFunc(Config.RestAPIPath+"/channels", api.GetChannels).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/channels", api.AddChannel).Methods("POST")
+	router.HandleFunc(Config.RestAPIPath+"/channels/{channelid}", api.EditChannel).Methods("PUT")
+	router.HandleFunc(Config.RestAPIPath+"/channels/{channelid}", api.DeleteChannel).Methods("DELETE")
+	router.HandleFunc(Config.RestAPIPath+"/channels/{channelid}/messages", api.GetChannelMessages).Methods("GET")
+	router.HandleFunc(Config.RestAPIPath+"/channels/{channelid}/test", api.TestChannel).Methods("POST")
 
 	fmt.Println("Server is running on: " + Config.RestAPIURL + ":" + Config.Port)
 
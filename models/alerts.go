@@ -0,0 +1,511 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// AlertConditions - conditions an alert can watch for
+var AlertConditions = []string{"price_above", "price_below", "percent_change", "cross_avg_buy"}
+
+// PortfolioAlertConditions - conditions on the whole portfolio, they have no coin
+var PortfolioAlertConditions = []string{"portfolio_worth_above", "portfolio_worth_below", "portfolio_profit_above", "portfolio_profit_below", "portfolio_daily_drop"}
+
+// Alert - condition on a coin price set by the user
+// Threshold is a price in EUR for price_above/price_below and a percentage for percent_change
+// (negative for drops), measured over WindowMinutes. cross_avg_buy fires when the price crosses
+// user's average buy price of the coin. An alert fires again only after CooldownMinutes and, when
+// Rearm is set, once its condition stopped being true; without Rearm it is disabled after firing.
+// Portfolio alerts compare Threshold with portfolio totals in EUR, or with the percentage the worth
+// dropped since yesterday's snapshot for portfolio_daily_drop; LastPrice then holds the last total.
+// When ChannelID is set, firings are also posted to that chat notification channel. Alerts created
+// for take-profit and stop-loss targets keep the holding in UserCoinID and follow its targets.
+type Alert struct {
+	AlertID         int     `json:"alertid"`
+	CoinID          int     `json:"coinid"`
+	UserCoinID      int     `json:"usercoinid"`
+	Symbol          string  `json:"symbol"`
+	Condition       string  `json:"condition"`
+	Threshold       float64 `json:"threshold"`
+	WindowMinutes   int     `json:"window_minutes"`
+	CooldownMinutes int     `json:"cooldown_minutes"`
+	ChannelID       int     `json:"channelid"`
+	Rearm           bool    `json:"rearm"`
+	Armed           bool    `json:"armed"`
+	Enabled         bool    `json:"enabled"`
+	LastPrice       float64 `json:"last_price"`
+	LastFired       string  `json:"last_fired"`
+	DateAdded       string  `json:"date_added"`
+}
+
+// AlertFiring - record of an alert that fired
+type AlertFiring struct {
+	AlertFiringID int     `json:"alertfiringid"`
+	AlertID       int     `json:"alertid"`
+	PriceEur      float64 `json:"priceeur"`
+	Message       string  `json:"message"`
+	DateFired     string  `json:"date_fired"`
+}
+
+// AlertFiredEvent - data sent to notification channels when alert fires
+type AlertFiredEvent struct {
+	Alert   Alert   `json:"alert"`
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+}
+
+// alertColumns - alert columns in the order they are scanned by scanAlert
+const alertColumns = "alertid, coinid, usercoinid, symbol, condition, threshold, window_minutes, cooldown_minutes, channelid, rearm, armed, enabled, last_price, last_fired, date_added"
+
+// AlertConditionExists - check if alert condition is supported
+func AlertConditionExists(condition string) bool {
+	for _, alertCondition := range AlertConditions {
+		if condition == alertCondition {
+			return true
+		}
+	}
+
+	return IsPortfolioAlert(condition)
+}
+
+// IsPortfolioAlert - check if condition is on the whole portfolio instead of a coin
+func IsPortfolioAlert(condition string) bool {
+	for _, alertCondition := range PortfolioAlertConditions {
+		if condition == alertCondition {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateAlert - check that alert settings make sense for its condition
+func ValidateAlert(alert Alert) error {
+	if !AlertConditionExists(alert.Condition) {
+		return errors.New("This alert condition does not exist.")
+	}
+
+	if (alert.Condition == "price_above" || alert.Condition == "price_below") && alert.Threshold <= 0 {
+		return errors.New("Price has to be greater than 0.")
+	}
+
+	if alert.Condition == "percent_change" && (alert.Threshold == 0 || alert.WindowMinutes <= 0) {
+		return errors.New("Percent change and time window have to be set.")
+	}
+
+	// Changes are measured against price history, which has one price per interval
+	if alert.Condition == "percent_change" && time.Duration(alert.WindowMinutes)*time.Minute < PriceHistoryInterval {
+		return fmt.Errorf("Time window has to be at least %d minutes.", int(PriceHistoryInterval.Minutes()))
+	}
+
+	if alert.Condition == "portfolio_daily_drop" && alert.Threshold <= 0 {
+		return errors.New("Percent drop has to be greater than 0.")
+	}
+
+	if alert.CooldownMinutes < 0 || alert.WindowMinutes < 0 {
+		return errors.New("Time window and cooldown can not be negative.")
+	}
+
+	return nil
+}
+
+// CreateAlert - create new alert
+func CreateAlert(DB queryer, userID int, alert Alert) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO alerts(userid, coinid, usercoinid, symbol, condition, threshold, window_minutes, cooldown_minutes, channelid, rearm, armed, enabled, date_added) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) returning alertid;",
+		userID, alert.CoinID, alert.UserCoinID, alert.Symbol, alert.Condition, alert.Threshold, alert.WindowMinutes, alert.CooldownMinutes, alert.ChannelID, alert.Rearm, true, alert.Enabled, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// UpdateAlert - update alert settings, changing them arms the alert again
+func UpdateAlert(DB *sql.DB, userID int, alert Alert) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE alerts SET condition = $1, threshold = $2, window_minutes = $3, cooldown_minutes = $4, channelid = $5, rearm = $6, enabled = $7, armed = $8 WHERE alertid = $9 AND userid = $10 returning alertid;",
+		alert.Condition, alert.Threshold, alert.WindowMinutes, alert.CooldownMinutes, alert.ChannelID, alert.Rearm, alert.Enabled, true, alert.AlertID, userID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// CountMatchingAlerts - count user's alerts with the same coin, condition and threshold
+func CountMatchingAlerts(DB queryer, userID int, alert Alert) int {
+	count := 0
+
+	row := DB.QueryRow("SELECT COUNT(*) FROM alerts WHERE userid = $1 AND symbol = $2 AND condition = $3 AND threshold = $4 AND window_minutes = $5",
+		userID, alert.Symbol, alert.Condition, alert.Threshold, alert.WindowMinutes)
+
+	err := row.Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count
+}
+
+// RemoveAlert - delete alert and its firings
+func RemoveAlert(DB *sql.DB, userID int, alertID int) bool {
+	result, err := DB.Exec("DELETE FROM alerts WHERE alertid = $1 AND userid = $2", alertID, userID)
+
+	if err != nil {
+		return false
+	}
+
+	if removed, _ := result.RowsAffected(); removed < 1 {
+		return false
+	}
+
+	_, err = DB.Exec("DELETE FROM alertfirings WHERE alertid = $1", alertID)
+
+	return err == nil
+}
+
+// GetUserAlert - get single alert of the user, AlertID is 0 if it does not exist
+func GetUserAlert(DB *sql.DB, userID int, alertID int) Alert {
+	var alert Alert
+
+	err := scanAlert(DB.QueryRow("SELECT "+alertColumns+" FROM alerts WHERE alertid = $1 AND userid = $2", alertID, userID), &alert)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return alert
+}
+
+// GetUserAlerts - get all alerts of the user
+func GetUserAlerts(DB *sql.DB, userID int) []Alert {
+	rows, err := DB.Query("SELECT "+alertColumns+" FROM alerts WHERE userid = $1 ORDER BY alertid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var alerts []Alert
+
+	// Foreach alert
+	for rows.Next() {
+		var alert Alert
+
+		if err = scanAlert(rows, &alert); err != nil {
+			panic(err)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts
+}
+
+// GetUserAlertFirings - latest firings of user's alerts, newest first
+func GetUserAlertFirings(DB *sql.DB, userID int, limit int) []AlertFiring {
+	rows, err := DB.Query("SELECT alertfiringid, alertid, priceeur, message, date_fired FROM alertfirings WHERE userid = $1 ORDER BY date_fired DESC, alertfiringid DESC LIMIT $2", userID, limit)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var firings []AlertFiring
+
+	// Foreach firing
+	for rows.Next() {
+		var firing AlertFiring
+
+		err = rows.Scan(&firing.AlertFiringID, &firing.AlertID, &firing.PriceEur, &firing.Message, &firing.DateFired)
+
+		if err != nil {
+			panic(err)
+		}
+
+		firings = append(firings, firing)
+	}
+
+	return firings
+}
+
+// GetUserAlertFiringsSince - user's alert firings after the date, oldest first
+func GetUserAlertFiringsSince(DB *sql.DB, userID int, since string) []AlertFiring {
+	rows, err := DB.Query("SELECT alertfiringid, alertid, priceeur, message, date_fired FROM alertfirings WHERE userid = $1 AND date_fired > $2 ORDER BY date_fired, alertfiringid", userID, since)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var firings []AlertFiring
+
+	// Foreach firing
+	for rows.Next() {
+		var firing AlertFiring
+
+		err = rows.Scan(&firing.AlertFiringID, &firing.AlertID, &firing.PriceEur, &firing.Message, &firing.DateFired)
+
+		if err != nil {
+			panic(err)
+		}
+
+		firings = append(firings, firing)
+	}
+
+	return firings
+}
+
+// CheckAlerts - check every enabled alert against the latest coin prices, called after each CMC sync
+func CheckAlerts(DB *sql.DB) {
+	rows, err := DB.Query("SELECT a.userid, a.alertid, a.coinid, a.usercoinid, a.symbol, a.condition, a.threshold, a.window_minutes, a.cooldown_minutes, a.channelid, a.rearm, a.armed, a.enabled, a.last_price, a.last_fired, a.date_added, c.priceeur FROM alerts a INNER JOIN coins c ON c.coinid = a.coinid WHERE a.enabled = true")
+
+	if err != nil {
+		panic(err)
+	}
+
+	type alertToCheck struct {
+		UserID   int
+		Alert    Alert
+		PriceEur float64
+	}
+
+	var alertsToCheck []alertToCheck
+
+	// Foreach alert
+	for rows.Next() {
+		var check alertToCheck
+
+		err = rows.Scan(&check.UserID, &check.Alert.AlertID, &check.Alert.CoinID, &check.Alert.UserCoinID, &check.Alert.Symbol, &check.Alert.Condition, &check.Alert.Threshold,
+			&check.Alert.WindowMinutes, &check.Alert.CooldownMinutes, &check.Alert.ChannelID, &check.Alert.Rearm, &check.Alert.Armed, &check.Alert.Enabled,
+			&check.Alert.LastPrice, &check.Alert.LastFired, &check.Alert.DateAdded, &check.PriceEur)
+
+		if err != nil {
+			panic(err)
+		}
+
+		alertsToCheck = append(alertsToCheck, check)
+	}
+
+	rows.Close()
+
+	for _, check := range alertsToCheck {
+		checkAlert(DB, check.UserID, check.Alert, check.PriceEur)
+	}
+}
+
+// CheckPortfolioAlerts - check user's portfolio alerts against totals from the latest sync
+func CheckPortfolioAlerts(DB *sql.DB, userID int, syncInfo SyncInfo) {
+	for _, alert := range GetUserAlerts(DB, userID) {
+		if !alert.Enabled || !IsPortfolioAlert(alert.Condition) {
+			continue
+		}
+
+		value := syncInfo.Worth
+
+		if alert.Condition == "portfolio_profit_above" || alert.Condition == "portfolio_profit_below" {
+			value = syncInfo.Profit
+		}
+
+		conditionMet, message := evaluatePortfolioAlert(DB, userID, alert, syncInfo)
+
+		updateAlertState(DB, userID, alert, value, conditionMet, message)
+	}
+}
+
+// checkAlert - evaluate single coin alert, record firing and update its state
+func checkAlert(DB *sql.DB, userID int, alert Alert, priceEur float64) {
+	conditionMet, message := evaluateAlert(DB, userID, alert, priceEur)
+
+	updateAlertState(DB, userID, alert, priceEur, conditionMet, message)
+}
+
+// updateAlertState - record firing if alert is armed and cooled down, then store its new state
+func updateAlertState(DB *sql.DB, userID int, alert Alert, value float64, conditionMet bool, message string) {
+	armed := alert.Armed
+	enabled := alert.Enabled
+	lastFired := alert.LastFired
+
+	if !conditionMet {
+		// Condition cleared, alert can fire again next time it is met
+		if alert.Rearm {
+			armed = true
+		}
+	} else if armed && alertCooledDown(alert) {
+		RecordAlertFiring(DB, userID, alert.AlertID, value, message)
+
+		alertFiredEvent := AlertFiredEvent{Alert: alert, Value: value, Message: message}
+
+		NotifyUser(DB, userID, "alert.fired", alertFiredEvent)
+
+		if alert.ChannelID > 0 {
+			QueueChannelMessage(DB, userID, alert.ChannelID, "alert.fired", alertFiredEvent)
+		}
+
+		lastFired = helpers.GetCurrentDateTime()
+
+		if !alert.Rearm {
+			enabled = false
+		} else if alert.Condition != "cross_avg_buy" {
+			// Crossing is an event, every other condition has to clear before firing again
+			armed = false
+		}
+	}
+
+	_, err := DB.Exec("UPDATE alerts SET armed = $1, enabled = $2, last_fired = $3, last_price = $4 WHERE alertid = $5",
+		armed, enabled, lastFired, value, alert.AlertID)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// evaluateAlert - check if alert's condition is met with the current price
+func evaluateAlert(DB *sql.DB, userID int, alert Alert, priceEur float64) (bool, string) {
+	switch alert.Condition {
+	case "price_above":
+		return priceEur >= alert.Threshold, fmt.Sprintf("%s price is above %s EUR (now %s EUR).", alert.Symbol, formatAlertNumber(alert.Threshold), formatAlertNumber(priceEur))
+	case "price_below":
+		return priceEur <= alert.Threshold, fmt.Sprintf("%s price is below %s EUR (now %s EUR).", alert.Symbol, formatAlertNumber(alert.Threshold), formatAlertNumber(priceEur))
+	case "percent_change":
+		window := time.Duration(alert.WindowMinutes) * time.Minute
+
+		if window < PriceHistoryInterval {
+			return false, ""
+		}
+
+		// Not evaluated until price history reaches back to the start of the window
+		previousPrice, ok := GetCoinPriceAt(DB, alert.CoinID, time.Now().Add(-window))
+
+		if !ok || previousPrice <= 0 {
+			return false, ""
+		}
+
+		change := (priceEur - previousPrice) / previousPrice * 100
+
+		conditionMet := (alert.Threshold > 0 && change >= alert.Threshold) || (alert.Threshold < 0 && change <= alert.Threshold)
+
+		return conditionMet, fmt.Sprintf("%s price changed by %s%% in the last %d minutes (now %s EUR).", alert.Symbol, formatAlertNumber(change), alert.WindowMinutes, formatAlertNumber(priceEur))
+	case "cross_avg_buy":
+		averageBuyPrice := GetAverageBuyPrice(DB, userID, alert.Symbol)
+
+		if averageBuyPrice <= 0 || alert.LastPrice <= 0 {
+			return false, ""
+		}
+
+		crossed := (alert.LastPrice < averageBuyPrice) != (priceEur < averageBuyPrice)
+
+		direction := "above"
+
+		if priceEur < averageBuyPrice {
+			direction = "below"
+		}
+
+		return crossed, fmt.Sprintf("%s price crossed %s your average buy price of %s EUR (now %s EUR).", alert.Symbol, direction, formatAlertNumber(averageBuyPrice), formatAlertNumber(priceEur))
+	}
+
+	return false, ""
+}
+
+// evaluatePortfolioAlert - check if portfolio alert's condition is met with the latest totals
+func evaluatePortfolioAlert(DB *sql.DB, userID int, alert Alert, syncInfo SyncInfo) (bool, string) {
+	switch alert.Condition {
+	case "portfolio_worth_above":
+		return syncInfo.Worth >= alert.Threshold, fmt.Sprintf("Your portfolio is worth more than %s EUR (now %s EUR).", formatAlertNumber(alert.Threshold), formatAlertNumber(syncInfo.Worth))
+	case "portfolio_worth_below":
+		return syncInfo.Worth <= alert.Threshold, fmt.Sprintf("Your portfolio is worth less than %s EUR (now %s EUR).", formatAlertNumber(alert.Threshold), formatAlertNumber(syncInfo.Worth))
+	case "portfolio_profit_above":
+		return syncInfo.Profit >= alert.Threshold, fmt.Sprintf("Your portfolio profit is above %s EUR (now %s EUR).", formatAlertNumber(alert.Threshold), formatAlertNumber(syncInfo.Profit))
+	case "portfolio_profit_below":
+		return syncInfo.Profit <= alert.Threshold, fmt.Sprintf("Your portfolio profit is below %s EUR (now %s EUR).", formatAlertNumber(alert.Threshold), formatAlertNumber(syncInfo.Profit))
+	case "portfolio_daily_drop":
+		yesterday := GetUserSnapshot(DB, userID, AllPortfolios, time.Now().AddDate(0, 0, -1).Format(SnapshotDateFormat))
+
+		if yesterday.Worth <= 0 {
+			return false, ""
+		}
+
+		drop := (yesterday.Worth - syncInfo.Worth) / yesterday.Worth * 100
+
+		return drop >= alert.Threshold, fmt.Sprintf("Your portfolio dropped by %s%% since yesterday (now %s EUR).", formatAlertNumber(drop), formatAlertNumber(syncInfo.Worth))
+	}
+
+	return false, ""
+}
+
+// alertCooledDown - check if cooldown since the last firing has passed
+func alertCooledDown(alert Alert) bool {
+	if alert.LastFired == "" || alert.CooldownMinutes <= 0 {
+		return true
+	}
+
+	lastFired, err := helpers.ParseDateTime(alert.LastFired)
+
+	if err != nil {
+		return true
+	}
+
+	return time.Since(lastFired) >= time.Duration(alert.CooldownMinutes)*time.Minute
+}
+
+// RecordAlertFiring - store that alert fired
+func RecordAlertFiring(DB *sql.DB, userID int, alertID int, priceEur float64, message string) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO alertfirings(alertid, userid, priceeur, message, date_fired) VALUES($1, $2, $3, $4, $5) returning alertfiringid;",
+		alertID, userID, priceEur, message, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// GetAverageBuyPrice - invested amount per coin of user's holding, 0 if user does not hold it
+func GetAverageBuyPrice(DB *sql.DB, userID int, symbol string) float64 {
+	var invested float64
+	var amount float64
+
+	err := DB.QueryRow("SELECT COALESCE(SUM(invested), 0), COALESCE(SUM(amount), 0) FROM usercoins WHERE userid = $1 AND symbol = $2", userID, symbol).Scan(&invested, &amount)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if amount <= 0 {
+		return 0
+	}
+
+	return invested / amount
+}
+
+// scanAlert - scan alertColumns into alert
+func scanAlert(row interface{ Scan(...interface{}) error }, alert *Alert) error {
+	return row.Scan(&alert.AlertID, &alert.CoinID, &alert.UserCoinID, &alert.Symbol, &alert.Condition, &alert.Threshold, &alert.WindowMinutes, &alert.CooldownMinutes,
+		&alert.ChannelID, &alert.Rearm, &alert.Armed, &alert.Enabled, &alert.LastPrice, &alert.LastFired, &alert.DateAdded)
+}
+
+// formatAlertNumber - round number for alert messages
+func formatAlertNumber(number float64) string {
+	if math.Abs(number) >= 1 {
+		return helpers.FormatNumber(roundNumber(number))
+	}
+
+	return helpers.FormatNumber(math.Round(number*1000000) / 1000000)
+}
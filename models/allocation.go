@@ -0,0 +1,270 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	_ "github.com/lib/pq"
+)
+
+// AllocationTarget - target weight of a coin in the portfolio, in percent
+type AllocationTarget struct {
+	Symbol string  `json:"symbol"`
+	Weight float64 `json:"weight"`
+}
+
+// AllocationSlice - share of the portfolio worth held in one coin, location or tag
+type AllocationSlice struct {
+	Name          string  `json:"name"`
+	Worth         float64 `json:"worth"`
+	Percent       float64 `json:"percent"`
+	TargetPercent float64 `json:"target_percent"`
+	Drift         float64 `json:"drift"`
+}
+
+// Allocation - portfolio worth broken down by coin, location and tag
+// Holdings with several tags count fully towards each of them, so tag percentages can add up to more than 100.
+type Allocation struct {
+	Worth     float64            `json:"worth"`
+	Coins     []AllocationSlice  `json:"coins"`
+	Locations []AllocationSlice  `json:"locations"`
+	Tags      []AllocationSlice  `json:"tags"`
+	Targets   []AllocationTarget `json:"targets"`
+}
+
+// RebalanceTrade - buy or sell needed to bring a coin to its target weight
+type RebalanceTrade struct {
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	Action        string  `json:"action"`
+	Value         float64 `json:"value"`
+	Amount        float64 `json:"amount"`
+	PriceEur      float64 `json:"priceeur"`
+	Fee           float64 `json:"fee"`
+	Percent       float64 `json:"percent"`
+	TargetPercent float64 `json:"target_percent"`
+}
+
+// Rebalance - trades that bring the portfolio to the target weights
+type Rebalance struct {
+	Worth   float64          `json:"worth"`
+	Trades  []RebalanceTrade `json:"trades"`
+	Skipped int              `json:"skipped"`
+	Fees    float64          `json:"fees"`
+}
+
+// ValidateAllocationTargets - check target coins exist, weights are positive and add up to 100
+func ValidateAllocationTargets(DB queryer, targets []AllocationTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	total := 0.0
+	symbols := []string{}
+
+	for _, target := range targets {
+		if name, _ := GetCoinBySymbol(DB, target.Symbol); name == "" {
+			return errors.New("Coin " + target.Symbol + " does not exist!")
+		}
+
+		if indexOf(symbols, target.Symbol) >= 0 {
+			return errors.New("Coin " + target.Symbol + " has more than one target.")
+		}
+
+		if target.Weight <= 0 {
+			return errors.New("Target weights have to be greater than 0.")
+		}
+
+		symbols = append(symbols, target.Symbol)
+		total += target.Weight
+	}
+
+	if math.Abs(total-100) > 0.01 {
+		return errors.New("Target weights have to add up to 100, not " + strconv.FormatFloat(total, 'f', -1, 64) + ".")
+	}
+
+	return nil
+}
+
+// GetAllocationTargets - target weights of the portfolio, AllPortfolios has its own targets
+func GetAllocationTargets(DB queryer, userID int, portfolioID int) []AllocationTarget {
+	rows, err := DB.Query("SELECT symbol, weight FROM allocationtargets WHERE userid = $1 AND portfolioid = $2 ORDER BY weight DESC, symbol", userID, portfolioID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	targets := []AllocationTarget{}
+
+	// Foreach target
+	for rows.Next() {
+		var target AllocationTarget
+
+		if err = rows.Scan(&target.Symbol, &target.Weight); err != nil {
+			panic(err)
+		}
+
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// SetAllocationTargets - replace target weights of the portfolio, empty list removes them
+func SetAllocationTargets(DB queryer, userID int, portfolioID int, targets []AllocationTarget) {
+	_, err := DB.Exec("DELETE FROM allocationtargets WHERE userid = $1 AND portfolioid = $2", userID, portfolioID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, target := range targets {
+		_, err = DB.Exec("INSERT INTO allocationtargets(userid, portfolioid, symbol, weight) VALUES($1, $2, $3, $4)", userID, portfolioID, target.Symbol, target.Weight)
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// BuildAllocation - break coins down by coin, location and tag and compare coins with the targets
+func BuildAllocation(coins []Coin, holdingTags map[int][]string, targets []AllocationTarget) Allocation {
+	allocation := Allocation{Targets: targets}
+
+	byCoin := map[string]float64{}
+	byLocation := map[string]float64{}
+	byTag := map[string]float64{}
+
+	for _, coin := range coins {
+		allocation.Worth += coin.Worth
+
+		byCoin[coin.Symbol] += coin.Worth
+		byLocation[coin.Lives] += coin.Worth
+
+		tags := holdingTags[coin.UserCoinID]
+
+		if len(tags) == 0 {
+			tags = []string{UntaggedName}
+		}
+
+		for _, tag := range tags {
+			byTag[tag] += coin.Worth
+		}
+	}
+
+	// Target coins that are not held yet show up with 0 worth
+	targetWeights := map[string]float64{}
+
+	for _, target := range targets {
+		targetWeights[target.Symbol] = target.Weight
+
+		if _, ok := byCoin[target.Symbol]; !ok {
+			byCoin[target.Symbol] = 0
+		}
+	}
+
+	allocation.Coins = allocationSlices(byCoin, allocation.Worth)
+	allocation.Locations = allocationSlices(byLocation, allocation.Worth)
+	allocation.Tags = allocationSlices(byTag, allocation.Worth)
+
+	if len(targets) > 0 {
+		for i, slice := range allocation.Coins {
+			allocation.Coins[i].TargetPercent = targetWeights[slice.Name]
+			allocation.Coins[i].Drift = roundNumber(slice.Percent - targetWeights[slice.Name])
+		}
+	}
+
+	return allocation
+}
+
+// BuildRebalance - trades that bring coins to the target weights
+// Coins and minTrade are in the currency the rate converts EUR into, prices of target coins that are not held are converted with it.
+// Trades smaller than minTrade are skipped, fee is estimated as feePercent of every trade.
+func BuildRebalance(DB *sql.DB, coins []Coin, targets []AllocationTarget, minTrade float64, feePercent float64, rate float64) Rebalance {
+	allocation := BuildAllocation(coins, nil, targets)
+
+	rebalance := Rebalance{Worth: allocation.Worth, Trades: []RebalanceTrade{}}
+
+	prices := map[string]float64{}
+	names := map[string]string{}
+
+	for _, coin := range coins {
+		prices[coin.Symbol] = coin.PriceEur
+		names[coin.Symbol] = coin.Name
+	}
+
+	for _, slice := range allocation.Coins {
+		value := allocation.Worth*slice.TargetPercent/100 - slice.Worth
+
+		if math.Abs(value) < 0.01 {
+			continue
+		}
+
+		if math.Abs(value) < minTrade {
+			rebalance.Skipped++
+
+			continue
+		}
+
+		if _, ok := prices[slice.Name]; !ok {
+			name, priceEur := GetCoinBySymbol(DB, slice.Name)
+
+			names[slice.Name], prices[slice.Name] = name, priceEur*rate
+		}
+
+		trade := RebalanceTrade{
+			Symbol:        slice.Name,
+			Name:          names[slice.Name],
+			Action:        "buy",
+			Value:         roundNumber(math.Abs(value)),
+			PriceEur:      prices[slice.Name],
+			Fee:           roundNumber(math.Abs(value) * feePercent / 100),
+			Percent:       slice.Percent,
+			TargetPercent: slice.TargetPercent,
+		}
+
+		if value < 0 {
+			trade.Action = "sell"
+		}
+
+		if trade.PriceEur > 0 {
+			trade.Amount = math.Abs(value) / trade.PriceEur
+		}
+
+		rebalance.Fees += trade.Fee
+		rebalance.Trades = append(rebalance.Trades, trade)
+	}
+
+	// Sells first, they fund the buys
+	sort.SliceStable(rebalance.Trades, func(i, j int) bool {
+		return rebalance.Trades[i].Action == "sell" && rebalance.Trades[j].Action == "buy"
+	})
+
+	rebalance.Fees = roundNumber(rebalance.Fees)
+
+	return rebalance
+}
+
+// allocationSlices - slices sorted by worth, largest first
+func allocationSlices(worthByName map[string]float64, total float64) []AllocationSlice {
+	slices := []AllocationSlice{}
+
+	for name, worth := range worthByName {
+		slices = append(slices, AllocationSlice{Name: name, Worth: roundNumber(worth), Percent: percentOf(worth, total)})
+	}
+
+	sort.Slice(slices, func(i, j int) bool {
+		if slices[i].Worth == slices[j].Worth {
+			return slices[i].Name < slices[j].Name
+		}
+
+		return slices[i].Worth > slices[j].Worth
+	})
+
+	return slices
+}
@@ -0,0 +1,362 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// BacktestStrategies - strategies that can be backtested
+var BacktestStrategies = []string{"lump_sum", "dca", "periodic", "threshold"}
+
+// BacktestMaxDays - longest date range a backtest can cover
+const BacktestMaxDays = 3650
+
+// Backtest - strategy simulated over stored price history, amounts are in EUR
+// Amount is invested on the first day, contribution every interval days. Periodic strategy rebalances to the
+// targets every interval days, threshold strategy whenever a coin drifts more than threshold percentage points.
+type Backtest struct {
+	Strategy     string             `json:"strategy"`
+	Targets      []AllocationTarget `json:"targets"`
+	From         string             `json:"from"`
+	To           string             `json:"to"`
+	Amount       float64            `json:"amount"`
+	Contribution float64            `json:"contribution"`
+	Interval     int                `json:"interval"`
+	Threshold    float64            `json:"threshold"`
+	FeePercent   float64            `json:"fee_percent"`
+}
+
+// BacktestPoint - simulated portfolio value at the end of a day and money put in that day
+type BacktestPoint struct {
+	Date     string  `json:"date"`
+	Value    float64 `json:"value"`
+	Invested float64 `json:"invested"`
+	Flow     float64 `json:"flow"`
+}
+
+// BacktestTrade - buy or sell made by the strategy
+type BacktestTrade struct {
+	Date     string  `json:"date"`
+	Symbol   string  `json:"symbol"`
+	Action   string  `json:"action"`
+	Amount   float64 `json:"amount"`
+	PriceEur float64 `json:"priceeur"`
+	Value    float64 `json:"value"`
+	Fee      float64 `json:"fee"`
+}
+
+// BacktestSummary - outcome of the backtest, returns are in percent
+type BacktestSummary struct {
+	Invested            float64  `json:"invested"`
+	FinalValue          float64  `json:"final_value"`
+	Profit              float64  `json:"profit"`
+	ProfitPercent       float64  `json:"profit_percent"`
+	TimeWeightedReturn  float64  `json:"time_weighted_return"`
+	MoneyWeightedReturn *float64 `json:"money_weighted_return"`
+	Volatility          float64  `json:"volatility"`
+	Sharpe              float64  `json:"sharpe"`
+	Drawdown            Drawdown `json:"drawdown"`
+	Trades              int      `json:"trades"`
+	Rebalances          int      `json:"rebalances"`
+	Fees                float64  `json:"fees"`
+}
+
+// BacktestResult - equity curve, trades and summary of the backtest
+type BacktestResult struct {
+	Backtest Backtest        `json:"backtest"`
+	Curve    []BacktestPoint `json:"curve"`
+	Trades   []BacktestTrade `json:"trades"`
+	Summary  BacktestSummary `json:"summary"`
+}
+
+// ValidateBacktest - check strategy, coins, date range and amounts
+func ValidateBacktest(DB *sql.DB, backtest Backtest) error {
+	if indexOf(BacktestStrategies, backtest.Strategy) < 0 {
+		return errors.New("Strategy has to be one of: " + strings.Join(BacktestStrategies, ", ") + ".")
+	}
+
+	if len(backtest.Targets) == 0 {
+		return errors.New("Please provide coins to backtest.")
+	}
+
+	if err := ValidateAllocationTargets(DB, backtest.Targets); err != nil {
+		return err
+	}
+
+	from, to, err := backtestDates(backtest)
+
+	if err != nil {
+		return err
+	}
+
+	if !from.Before(to) || to.After(dayOf(time.Now())) {
+		return errors.New("Date range has to start before it ends and can not end in the future.")
+	}
+
+	if to.Sub(from).Hours()/24 > BacktestMaxDays {
+		return errors.New("Backtests can cover at most 10 years.")
+	}
+
+	if backtest.Amount < 0 || backtest.Contribution < 0 {
+		return errors.New("Amounts can not be negative.")
+	}
+
+	if backtest.Strategy == "dca" && backtest.Contribution <= 0 {
+		return errors.New("Please provide the contribution made every interval.")
+	}
+
+	if backtest.Strategy != "dca" && backtest.Amount <= 0 {
+		return errors.New("Please provide the amount invested on the first day.")
+	}
+
+	if (backtest.Contribution > 0 || backtest.Strategy == "periodic") && backtest.Interval < 1 {
+		return errors.New("Interval has to be at least 1 day.")
+	}
+
+	if backtest.Strategy == "threshold" && backtest.Threshold <= 0 {
+		return errors.New("Threshold has to be greater than 0.")
+	}
+
+	if backtest.FeePercent < 0 || backtest.FeePercent >= 100 {
+		return errors.New("Fee has to be between 0 and 100 percent.")
+	}
+
+	return nil
+}
+
+// RunBacktest - simulate the strategy with daily prices from price history
+// Date range has to start on or after the first stored price of every coin, prices are never filled in backwards.
+func RunBacktest(DB *sql.DB, backtest Backtest) (BacktestResult, error) {
+	from, to, err := backtestDates(backtest)
+
+	if err != nil {
+		return BacktestResult{}, err
+	}
+
+	for _, target := range backtest.Targets {
+		start, ok := GetCoinHistoryStart(DB, GetCoinIDBySymbol(DB, target.Symbol))
+
+		if !ok {
+			return BacktestResult{}, errors.New("There is no price history for " + target.Symbol + " yet.")
+		}
+
+		if start.After(from) {
+			return BacktestResult{}, errors.New("Price history for " + target.Symbol + " starts on " + start.Format(SnapshotDateFormat) + ", please start the backtest on that day or later.")
+		}
+	}
+
+	days := []time.Time{}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	prices := [][]float64{}
+
+	for _, target := range backtest.Targets {
+		targetPrices, ok := dailyCoinPrices(DB, GetCoinIDBySymbol(DB, target.Symbol), days)
+
+		if !ok {
+			return BacktestResult{}, errors.New("There is no price history for " + target.Symbol + " yet.")
+		}
+
+		prices = append(prices, targetPrices)
+	}
+
+	return simulateBacktest(backtest, days, prices), nil
+}
+
+// backtestDates - first and last day of the backtest
+func backtestDates(backtest Backtest) (time.Time, time.Time, error) {
+	from, err := time.Parse(SnapshotDateFormat, backtest.From)
+
+	if err != nil {
+		return from, from, errors.New("Dates have to be in " + SnapshotDateFormat + " format.")
+	}
+
+	to, err := time.Parse(SnapshotDateFormat, backtest.To)
+
+	if err != nil {
+		return from, to, errors.New("Dates have to be in " + SnapshotDateFormat + " format.")
+	}
+
+	return from, to, nil
+}
+
+// simulateBacktest - run the strategy day by day, prices has the daily prices of every target coin
+// Money that can not be spent because a coin has no price that day stays as cash.
+func simulateBacktest(backtest Backtest, days []time.Time, prices [][]float64) BacktestResult {
+	result := BacktestResult{Backtest: backtest, Curve: []BacktestPoint{}, Trades: []BacktestTrade{}}
+
+	units := make([]float64, len(backtest.Targets))
+	cash := 0.0
+	invested := 0.0
+
+	points := []valuePoint{}
+	flows := []cashFlow{}
+
+	trade := func(i int, j int, action string, value float64) {
+		if value < 0.01 || prices[j][i] <= 0 {
+			return
+		}
+
+		fee := value * backtest.FeePercent / 100
+		amount := value / prices[j][i]
+
+		if action == "buy" {
+			amount = (value - fee) / prices[j][i]
+			units[j] += amount
+			cash -= value
+		} else {
+			units[j] -= amount
+			cash += value - fee
+		}
+
+		result.Summary.Fees += fee
+
+		result.Trades = append(result.Trades, BacktestTrade{
+			Date:     days[i].Format(SnapshotDateFormat),
+			Symbol:   backtest.Targets[j].Symbol,
+			Action:   action,
+			Amount:   amount,
+			PriceEur: prices[j][i],
+			Value:    roundNumber(value),
+			Fee:      roundNumber(fee),
+		})
+	}
+
+	for i, day := range days {
+		flow := 0.0
+
+		if i == 0 {
+			flow += backtest.Amount
+		}
+
+		if backtest.Contribution > 0 && i%backtest.Interval == 0 {
+			flow += backtest.Contribution
+		}
+
+		cash += flow
+		invested += flow
+
+		if flow > 0 {
+			flows = append(flows, cashFlow{Date: day, Amount: -flow})
+
+			for j, target := range backtest.Targets {
+				trade(i, j, "buy", flow*target.Weight/100)
+			}
+		}
+
+		worth := cash
+		worths := make([]float64, len(units))
+
+		for j := range units {
+			worths[j] = units[j] * prices[j][i]
+			worth += worths[j]
+		}
+
+		rebalance := false
+
+		if backtest.Strategy == "periodic" && i > 0 && i%backtest.Interval == 0 {
+			rebalance = true
+		}
+
+		if backtest.Strategy == "threshold" && worth > 0 {
+			for j, target := range backtest.Targets {
+				if math.Abs(worths[j]/worth*100-target.Weight) > backtest.Threshold {
+					rebalance = true
+				}
+			}
+		}
+
+		if rebalance && worth > 0 {
+			tradesBefore := len(result.Trades)
+
+			// Sell coins above their target first, then spend the cash on coins below it
+			for j, target := range backtest.Targets {
+				trade(i, j, "sell", worths[j]-worth*target.Weight/100)
+			}
+
+			missing := 0.0
+
+			for j, target := range backtest.Targets {
+				missing += math.Max(worth*target.Weight/100-worths[j], 0)
+			}
+
+			if missing > 0 {
+				spend := math.Min(cash, missing)
+
+				for j, target := range backtest.Targets {
+					trade(i, j, "buy", spend*math.Max(worth*target.Weight/100-worths[j], 0)/missing)
+				}
+			}
+
+			if len(result.Trades) > tradesBefore {
+				result.Summary.Rebalances++
+			}
+		}
+
+		value := cash
+
+		for j := range units {
+			value += units[j] * prices[j][i]
+		}
+
+		points = append(points, valuePoint{Date: day, Value: value, Flow: flow})
+
+		result.Curve = append(result.Curve, BacktestPoint{
+			Date:     day.Format(SnapshotDateFormat),
+			Value:    roundNumber(value),
+			Invested: roundNumber(invested),
+			Flow:     roundNumber(flow),
+		})
+	}
+
+	last := points[len(points)-1]
+
+	// First day's money is the starting value, not a flow within the curve
+	points[0].Flow = 0
+
+	dates := []time.Time{points[0].Date}
+	returns := []float64{}
+
+	for i := 1; i < len(points); i++ {
+		start := points[i-1].Value + points[i].Flow
+
+		if start <= 0 {
+			continue
+		}
+
+		dates = append(dates, points[i].Date)
+		returns = append(returns, (points[i].Value-start)/start)
+	}
+
+	metrics := riskMetrics(dates, returns, 0)
+
+	result.Summary.Invested = roundNumber(invested)
+	result.Summary.FinalValue = roundNumber(last.Value)
+	result.Summary.Profit = roundNumber(last.Value - invested)
+	result.Summary.TimeWeightedReturn = roundNumber(timeWeightedReturn(points) * 100)
+	result.Summary.Volatility = metrics.Volatility
+	result.Summary.Sharpe = metrics.Sharpe
+	result.Summary.Drawdown = metrics.Drawdown
+	result.Summary.Trades = len(result.Trades)
+	result.Summary.Fees = roundNumber(result.Summary.Fees)
+
+	if invested > 0 {
+		result.Summary.ProfitPercent = roundNumber((last.Value - invested) / invested * 100)
+	}
+
+	if irr, ok := xirr(append(flows, cashFlow{Date: last.Date, Amount: last.Value})); ok {
+		irr = roundNumber(irr * 100)
+
+		result.Summary.MoneyWeightedReturn = &irr
+	}
+
+	return result
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulateBacktest(t *testing.T) {
+	days := []time.Time{day(2021, 1, 1), day(2021, 1, 2), day(2021, 1, 3)}
+
+	tests := []struct {
+		name       string
+		backtest   Backtest
+		prices     [][]float64
+		want       BacktestSummary
+		finalCurve BacktestPoint
+	}{
+		{
+			name:       "lump sum doubles",
+			backtest:   Backtest{Strategy: "lump_sum", Targets: []AllocationTarget{{Symbol: "BTC", Weight: 100}}, Amount: 1000},
+			prices:     [][]float64{{100, 150, 200}},
+			want:       BacktestSummary{Invested: 1000, FinalValue: 2000, Profit: 1000, ProfitPercent: 100, TimeWeightedReturn: 100, Trades: 1},
+			finalCurve: BacktestPoint{Date: "2021.01.03", Value: 2000, Invested: 1000},
+		},
+		{
+			name:       "fee is taken from the buy",
+			backtest:   Backtest{Strategy: "lump_sum", Targets: []AllocationTarget{{Symbol: "BTC", Weight: 100}}, Amount: 1000, FeePercent: 1},
+			prices:     [][]float64{{100, 100, 100}},
+			want:       BacktestSummary{Invested: 1000, FinalValue: 990, Profit: -10, ProfitPercent: -1, Trades: 1, Fees: 10},
+			finalCurve: BacktestPoint{Date: "2021.01.03", Value: 990, Invested: 1000},
+		},
+		{
+			name:       "contributions every day",
+			backtest:   Backtest{Strategy: "dca", Targets: []AllocationTarget{{Symbol: "BTC", Weight: 100}}, Contribution: 100, Interval: 1},
+			prices:     [][]float64{{100, 100, 100}},
+			want:       BacktestSummary{Invested: 300, FinalValue: 300, Trades: 3},
+			finalCurve: BacktestPoint{Date: "2021.01.03", Value: 300, Invested: 300, Flow: 100},
+		},
+		{
+			name: "periodic rebalance sells the winner",
+			backtest: Backtest{Strategy: "periodic", Targets: []AllocationTarget{{Symbol: "BTC", Weight: 50}, {Symbol: "ETH", Weight: 50}},
+				Amount: 1000, Interval: 2},
+			prices:     [][]float64{{100, 200, 200}, {100, 100, 100}},
+			want:       BacktestSummary{Invested: 1000, FinalValue: 1500, Profit: 500, ProfitPercent: 50, TimeWeightedReturn: 50, Trades: 4, Rebalances: 1},
+			finalCurve: BacktestPoint{Date: "2021.01.03", Value: 1500, Invested: 1000},
+		},
+		{
+			name: "threshold rebalance waits for the drift",
+			backtest: Backtest{Strategy: "threshold", Targets: []AllocationTarget{{Symbol: "BTC", Weight: 50}, {Symbol: "ETH", Weight: 50}},
+				Amount: 1000, Threshold: 20},
+			prices:     [][]float64{{100, 120, 400}, {100, 100, 100}},
+			want:       BacktestSummary{Invested: 1000, FinalValue: 2500, Profit: 1500, ProfitPercent: 150, TimeWeightedReturn: 150, Trades: 4, Rebalances: 1},
+			finalCurve: BacktestPoint{Date: "2021.01.03", Value: 2500, Invested: 1000},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := simulateBacktest(test.backtest, days, test.prices)
+
+			got := result.Summary
+
+			// Ratios, money-weighted return and drawdown have their own tests
+			got.Volatility, got.Sharpe, got.MoneyWeightedReturn, got.Drawdown = 0, 0, nil, Drawdown{}
+
+			if got != test.want {
+				t.Errorf("simulateBacktest() summary = %+v, want %+v", got, test.want)
+			}
+
+			if len(result.Curve) != len(days) {
+				t.Fatalf("simulateBacktest() curve has %d points, want %d", len(result.Curve), len(days))
+			}
+
+			if last := result.Curve[len(result.Curve)-1]; last != test.finalCurve {
+				t.Errorf("simulateBacktest() last point = %+v, want %+v", last, test.finalCurve)
+			}
+		})
+	}
+}
+
+func TestSimulateBacktestDrawdown(t *testing.T) {
+	days := []time.Time{day(2021, 1, 1), day(2021, 1, 2), day(2021, 1, 3)}
+	backtest := Backtest{Strategy: "lump_sum", Targets: []AllocationTarget{{Symbol: "BTC", Weight: 100}}, Amount: 1000}
+
+	result := simulateBacktest(backtest, days, [][]float64{{100, 50, 100}})
+
+	want := Drawdown{MaxDrawdown: 50, PeakDate: "2021.01.01", TroughDate: "2021.01.02", RecoveryDate: "2021.01.03"}
+
+	if result.Summary.Drawdown != want {
+		t.Errorf("simulateBacktest() drawdown = %+v, want %+v", result.Summary.Drawdown, want)
+	}
+}
@@ -0,0 +1,523 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// BackupVersion - current version of the backup format, bump it whenever Backup changes
+// Version 2 added alerts, version 3 added portfolios, version 4 added holding targets,
+// version 5 added holding notes and tags, version 6 linked target alerts to holdings,
+// version 7 added watchlist, allocation targets and plans.
+const BackupVersion = 7
+
+// Backup - everything needed to move user's account to another instance
+// Notification channels, webhooks and portfolio members are not part of it.
+type Backup struct {
+	Version           int                          `json:"version"`
+	DateCreated       string                       `json:"date_created"`
+	Settings          []UserSetting                `json:"settings"`
+	Portfolios        []Portfolio                  `json:"portfolios"`
+	Holdings          []Coin                       `json:"holdings"`
+	Transactions      []Transaction                `json:"transactions"`
+	Alerts            []Alert                      `json:"alerts"`
+	Watchlist         []WatchlistEntry             `json:"watchlist"`
+	AllocationTargets []PortfolioAllocationTargets `json:"allocation_targets"`
+	Plans             []Plan                       `json:"plans"`
+}
+
+// PortfolioAllocationTargets - allocation targets of one portfolio, AllPortfolios has its own targets
+type PortfolioAllocationTargets struct {
+	PortfolioID int                `json:"portfolioid"`
+	Targets     []AllocationTarget `json:"targets"`
+}
+
+// RestoreResult - what was restored from the backup
+type RestoreResult struct {
+	Settings          int `json:"settings"`
+	Portfolios        int `json:"portfolios"`
+	Holdings          int `json:"holdings"`
+	Transactions      int `json:"transactions"`
+	Alerts            int `json:"alerts"`
+	Watchlist         int `json:"watchlist"`
+	AllocationTargets int `json:"allocation_targets"`
+	Plans             int `json:"plans"`
+	Skipped           int `json:"skipped"`
+}
+
+// CreateBackup - backup of user's account in the current format
+func CreateBackup(DB *sql.DB, userID int) Backup {
+	backup := Backup{
+		Version:      BackupVersion,
+		DateCreated:  helpers.GetCurrentDateTime(),
+		Settings:     GetUserSettings(DB, userID),
+		Portfolios:   GetUserPortfolios(DB, userID),
+		Holdings:     GetUserHoldings(DB, userID, AllPortfolios),
+		Transactions: GetUserTransactions(DB, userID, AllPortfolios),
+		Alerts:       GetUserAlerts(DB, userID),
+		Watchlist:    GetUserWatchlist(DB, userID),
+		Plans:        GetPortfolioPlans(DB, userID, AllPortfolios),
+	}
+
+	if backup.Settings == nil {
+		backup.Settings = []UserSetting{}
+	}
+
+	if backup.Portfolios == nil {
+		backup.Portfolios = []Portfolio{}
+	}
+
+	if backup.Holdings == nil {
+		backup.Holdings = []Coin{}
+	}
+
+	holdingTags := GetHoldingTags(DB, userID)
+
+	for i := range backup.Holdings {
+		backup.Holdings[i].Tags = holdingTags[backup.Holdings[i].UserCoinID]
+
+		if backup.Holdings[i].Tags == nil {
+			backup.Holdings[i].Tags = []string{}
+		}
+	}
+
+	if backup.Transactions == nil {
+		backup.Transactions = []Transaction{}
+	}
+
+	if backup.Alerts == nil {
+		backup.Alerts = []Alert{}
+	}
+
+	if backup.Watchlist == nil {
+		backup.Watchlist = []WatchlistEntry{}
+	}
+
+	backup.AllocationTargets = []PortfolioAllocationTargets{}
+
+	for _, portfolioID := range append([]int{AllPortfolios}, getPortfolioIDs(backup.Portfolios)...) {
+		if targets := GetAllocationTargets(DB, userID, portfolioID); len(targets) > 0 {
+			backup.AllocationTargets = append(backup.AllocationTargets, PortfolioAllocationTargets{PortfolioID: portfolioID, Targets: targets})
+		}
+	}
+
+	return backup
+}
+
+// ValidateBackup - check that backup can be restored with the current schema
+func ValidateBackup(backup Backup) error {
+	if backup.Version < 1 || backup.Version > BackupVersion {
+		return errors.New("Backup version " + strconv.Itoa(backup.Version) + " is not supported.")
+	}
+
+	portfolioIDs := map[int]bool{AllPortfolios: true}
+
+	for i, portfolio := range backup.Portfolios {
+		if err := ValidatePortfolio(portfolio); err != nil {
+			return errors.New("Portfolio " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+
+		portfolioIDs[portfolio.PortfolioID] = true
+	}
+
+	holdingIDs := map[int]bool{}
+
+	for i, holding := range backup.Holdings {
+		// Holdings from backups made before portfolios existed have no portfolio and go to the default one
+		if !portfolioIDs[holding.PortfolioID] {
+			return errors.New("Holding " + strconv.Itoa(i+1) + " does not belong to any portfolio in the backup.")
+		}
+
+		if holding.Name == "" || holding.Symbol == "" || holding.Lives == "" {
+			return errors.New("Holding " + strconv.Itoa(i+1) + " is missing name, symbol or location.")
+		}
+
+		if holding.Amount < 0 || holding.Invested < 0 {
+			return errors.New("Holding " + strconv.Itoa(i+1) + " has negative amount or invested value.")
+		}
+
+		if err := ValidateHoldingTargets(holdingTarget(holding.TakeProfit), holdingTarget(holding.StopLoss)); err != nil {
+			return errors.New("Holding " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+
+		if err := ValidateNotes(holding.Notes); err != nil {
+			return errors.New("Holding " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+
+		if _, err := NormalizeTags(holding.Tags); err != nil {
+			return errors.New("Holding " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+
+		holdingIDs[holding.UserCoinID] = true
+	}
+
+	for i, transaction := range backup.Transactions {
+		if transaction.Type != "buy" && transaction.Type != "sell" {
+			return errors.New("Transaction " + strconv.Itoa(i+1) + " has to be buy or sell.")
+		}
+
+		if transaction.Name == "" || transaction.Symbol == "" || transaction.Amount <= 0 {
+			return errors.New("Transaction " + strconv.Itoa(i+1) + " is missing name, symbol or amount.")
+		}
+
+		if !holdingIDs[transaction.UserCoinID] {
+			return errors.New("Transaction " + strconv.Itoa(i+1) + " does not belong to any holding in the backup.")
+		}
+	}
+
+	for i, alert := range backup.Alerts {
+		if alert.Symbol == "" && !IsPortfolioAlert(alert.Condition) {
+			return errors.New("Alert " + strconv.Itoa(i+1) + " is missing symbol.")
+		}
+
+		if err := ValidateAlert(alert); err != nil {
+			return errors.New("Alert " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+	}
+
+	for i, setting := range backup.Settings {
+		if setting.Name == "" {
+			return errors.New("Setting " + strconv.Itoa(i+1) + " is missing name.")
+		}
+	}
+
+	for i, entry := range backup.Watchlist {
+		if entry.Symbol == "" {
+			return errors.New("Watchlist entry " + strconv.Itoa(i+1) + " is missing symbol.")
+		}
+
+		if err := ValidateNotes(entry.Notes); err != nil {
+			return errors.New("Watchlist entry " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+	}
+
+	for i, allocationTargets := range backup.AllocationTargets {
+		if !portfolioIDs[allocationTargets.PortfolioID] {
+			return errors.New("Allocation targets " + strconv.Itoa(i+1) + " do not belong to any portfolio in the backup.")
+		}
+	}
+
+	for i, plan := range backup.Plans {
+		if !portfolioIDs[plan.PortfolioID] {
+			return errors.New("Plan " + strconv.Itoa(i+1) + " does not belong to any portfolio in the backup.")
+		}
+
+		if plan.Name == "" || plan.Symbol == "" {
+			return errors.New("Plan " + strconv.Itoa(i+1) + " is missing name or symbol.")
+		}
+
+		if err := ValidatePlan(plan); err != nil {
+			return errors.New("Plan " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// AccountIsEmpty - check if user has no holdings and no transactions yet
+func AccountIsEmpty(DB *sql.DB, userID int) bool {
+	count := 0
+
+	row := DB.QueryRow("SELECT (SELECT COUNT(*) FROM usercoins WHERE userid = $1) + (SELECT COUNT(*) FROM transactions WHERE userid = $1)", userID)
+
+	err := row.Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count == 0
+}
+
+// RestoreBackup - restore backup into user's account in one DB transaction, nothing is restored if it fails midway
+// When merging, holdings the user has already and transactions restored or imported already are
+// skipped, and existing settings are kept. New transactions of holdings the user has already are
+// applied to them, so their amount and invested value match the ledger.
+func RestoreBackup(DB *sql.DB, userID int, backup Backup, merge bool) RestoreResult {
+	tx, err := DB.Begin()
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer tx.Rollback()
+
+	result := restoreBackup(tx, userID, backup, merge)
+
+	if err = tx.Commit(); err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// restoreBackup - restore backup through DB, which is an open DB transaction
+func restoreBackup(DB queryer, userID int, backup Backup, merge bool) RestoreResult {
+	var result RestoreResult
+
+	for _, setting := range backup.Settings {
+		// Notification channels are not part of the backup, digests go out by email until a channel is picked again
+		if setting.Name == "digest_channelid" || (merge && GetUserSetting(DB, userID, setting.Name) != "") {
+			result.Skipped++
+
+			continue
+		}
+
+		// Endpoints have to be unique across the instance
+		if setting.Name == "profits_endpoint" && CountProfitsEndpointUsers(DB, userID, setting.Value) > 0 {
+			setting.Value = GenerateEndpoint()
+		}
+
+		SetUserSetting(DB, userID, setting.Name, setting.Value)
+
+		result.Settings++
+	}
+
+	// Portfolio IDs from the backup mapped to portfolio IDs in this account, portfolios are matched by name
+	portfolioIDs := map[int]int{}
+
+	for _, portfolio := range backup.Portfolios {
+		for _, existing := range GetUserPortfolios(DB, userID) {
+			if existing.Name == portfolio.Name {
+				portfolioIDs[portfolio.PortfolioID] = existing.PortfolioID
+			}
+		}
+
+		if portfolioIDs[portfolio.PortfolioID] > 0 {
+			result.Skipped++
+
+			continue
+		}
+
+		portfolioIDs[portfolio.PortfolioID] = CreatePortfolio(DB, userID, portfolio)
+
+		result.Portfolios++
+	}
+
+	restorePortfolioID := func(portfolioID int) int {
+		if portfolioIDs[portfolioID] < 1 {
+			portfolioIDs[portfolioID] = CreateDefaultPortfolio(DB, userID).PortfolioID
+		}
+
+		return portfolioIDs[portfolioID]
+	}
+
+	// Holding IDs from the backup mapped to holding IDs in this account, and holdings the user had already
+	holdingIDs := map[int]int{}
+	existingHoldings := map[int]bool{}
+
+	for _, holding := range backup.Holdings {
+		holding.PortfolioID = restorePortfolioID(holding.PortfolioID)
+
+		existingID := GetUserCoinID(DB, userID, holding.PortfolioID, holding.Name, holding.Symbol)
+
+		if existingID > 0 {
+			holdingIDs[holding.UserCoinID] = existingID
+			existingHoldings[holding.UserCoinID] = true
+			result.Skipped++
+
+			continue
+		}
+
+		holdingIDs[holding.UserCoinID] = RestoreCoin(DB, userID, holding)
+
+		if tags, _ := NormalizeTags(holding.Tags); len(tags) > 0 {
+			SetHoldingTags(DB, userID, holdingIDs[holding.UserCoinID], tags)
+		}
+
+		result.Holdings++
+	}
+
+	// Transactions without a hash get one from their contents, so merging the same backup again skips them
+	unhashed := map[string]int{}
+
+	for _, transaction := range backup.Transactions {
+		if transaction.Hash == "" {
+			transaction.Hash = restoredTransactionHash(transaction, unhashed)
+		}
+
+		if CheckTransactionHashExists(DB, userID, transaction.Hash) > 0 {
+			result.Skipped++
+
+			continue
+		}
+
+		transaction.PortfolioID = restorePortfolioID(transaction.PortfolioID)
+
+		// Restored holdings come with their amount and invested value, holdings the user had already are updated
+		if existingHoldings[transaction.UserCoinID] {
+			if applyTransaction(DB, userID, transaction) < 1 {
+				result.Skipped++
+
+				continue
+			}
+		} else {
+			transaction.UserCoinID = holdingIDs[transaction.UserCoinID]
+
+			CreateTransaction(DB, userID, transaction)
+		}
+
+		result.Transactions++
+	}
+
+	for _, alert := range backup.Alerts {
+		// Coin IDs differ between instances, alerts are matched to coins by symbol
+		alert.CoinID = 0
+
+		// Notification channels are not part of the backup
+		alert.ChannelID = 0
+
+		// Target alerts follow the restored holding, 0 when the holding is not in the backup
+		alert.UserCoinID = holdingIDs[alert.UserCoinID]
+
+		if !IsPortfolioAlert(alert.Condition) {
+			alert.CoinID = GetCoinIDBySymbol(DB, alert.Symbol)
+		}
+
+		if (alert.CoinID < 1 && !IsPortfolioAlert(alert.Condition)) || (merge && CountMatchingAlerts(DB, userID, alert) > 0) {
+			result.Skipped++
+
+			continue
+		}
+
+		CreateAlert(DB, userID, alert)
+
+		result.Alerts++
+	}
+
+	for _, entry := range backup.Watchlist {
+		// Coin IDs differ between instances, watched coins are matched by symbol
+		coinID := GetCoinIDBySymbol(DB, entry.Symbol)
+
+		if coinID < 1 || IsCoinWatched(DB, userID, coinID) {
+			result.Skipped++
+
+			continue
+		}
+
+		AddToWatchlist(DB, userID, coinID, entry.Notes)
+
+		result.Watchlist++
+	}
+
+	for _, allocationTargets := range backup.AllocationTargets {
+		portfolioID := AllPortfolios
+
+		if allocationTargets.PortfolioID != AllPortfolios {
+			portfolioID = restorePortfolioID(allocationTargets.PortfolioID)
+		}
+
+		if (merge && len(GetAllocationTargets(DB, userID, portfolioID)) > 0) || ValidateAllocationTargets(DB, allocationTargets.Targets) != nil {
+			result.Skipped++
+
+			continue
+		}
+
+		SetAllocationTargets(DB, userID, portfolioID, allocationTargets.Targets)
+
+		result.AllocationTargets++
+	}
+
+	for _, plan := range backup.Plans {
+		plan.OwnerID = userID
+		plan.PortfolioID = restorePortfolioID(plan.PortfolioID)
+
+		if merge && countMatchingPlans(DB, plan) > 0 {
+			result.Skipped++
+
+			continue
+		}
+
+		// Runs missed while the account was moved are not made up for, like any other missed run
+		if nextRun, err := helpers.ParseDateTime(plan.NextRun); err == nil && nextRun.Before(time.Now()) {
+			plan.NextRun = helpers.FormatDateTime(nextPlanRun(plan.Schedule, nextRun, time.Now()))
+		}
+
+		CreatePlan(DB, plan)
+
+		result.Plans++
+	}
+
+	return result
+}
+
+// countMatchingPlans - count owner's plans of the same coin and schedule in the plan's portfolio
+func countMatchingPlans(DB queryer, plan Plan) int {
+	count := 0
+
+	err := DB.QueryRow("SELECT COUNT(*) FROM plans WHERE userid = $1 AND portfolioid = $2 AND symbol = $3 AND schedule = $4",
+		plan.OwnerID, plan.PortfolioID, plan.Symbol, plan.Schedule).Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count
+}
+
+// getPortfolioIDs - IDs of the portfolios
+func getPortfolioIDs(portfolios []Portfolio) []int {
+	ids := []int{}
+
+	for _, portfolio := range portfolios {
+		ids = append(ids, portfolio.PortfolioID)
+	}
+
+	return ids
+}
+
+// GetUserCoinID - ID of user's holding of this coin in the portfolio, 0 if user does not have it
+func GetUserCoinID(DB queryer, userID int, portfolioID int, name string, symbol string) int {
+	userCoinID := 0
+
+	err := DB.QueryRow("SELECT usercoinid FROM usercoins WHERE userid = $1 AND portfolioid = $2 AND name = $3 AND symbol = $4", userID, portfolioID, name, symbol).Scan(&userCoinID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return userCoinID
+}
+
+// RestoreCoin - insert holding from the backup, keeping its dates, targets and notes
+func RestoreCoin(DB queryer, userID int, coin Coin) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO usercoins(userid, portfolioid, name, symbol, invested, amount, madelost, worth, priceeur, lives, take_profit, stop_loss, notes, date_added, date_updated) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) returning usercoinid;",
+		userID, coin.PortfolioID, coin.Name, coin.Symbol, coin.Invested, coin.Amount, coin.MadeLost, coin.Worth, coin.PriceEur, coin.Lives, holdingTarget(coin.TakeProfit), holdingTarget(coin.StopLoss), coin.Notes, coin.DateAdded, coin.DateUpdated).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// holdingTarget - price of the target, 0 when the holding has no target
+func holdingTarget(target *Target) float64 {
+	if target == nil {
+		return 0
+	}
+
+	return target.Price
+}
+
+// restoredTransactionHash - hash of transaction's date, symbol, type, amount and price
+// Identical transactions are told apart by how many came before them in the backup.
+func restoredTransactionHash(transaction Transaction, seen map[string]int) string {
+	key := fmt.Sprintf("restore|%s|%s|%s|%g|%g", transaction.DateTransaction, transaction.Symbol, transaction.Type, transaction.Amount, transaction.PriceEur)
+
+	seen[key]++
+
+	hash := sha256.Sum256([]byte(key + "|" + strconv.Itoa(seen[key])))
+
+	return hex.EncodeToString(hash[:])
+}
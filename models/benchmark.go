@@ -0,0 +1,212 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EquityPoint - portfolio and benchmark value at the end of a day
+type EquityPoint struct {
+	Date      string  `json:"date"`
+	Portfolio float64 `json:"portfolio"`
+	Benchmark float64 `json:"benchmark"`
+	Flow      float64 `json:"flow"`
+}
+
+// BenchmarkComparison - portfolio compared with the same cash flows put into the benchmark
+// Returns are time-weighted, in percent; relative return is portfolio return minus benchmark return.
+type BenchmarkComparison struct {
+	Benchmark       []AllocationTarget `json:"benchmark"`
+	Period          string             `json:"period"`
+	From            string             `json:"from"`
+	To              string             `json:"to"`
+	PortfolioValue  float64            `json:"portfolio_value"`
+	BenchmarkValue  float64            `json:"benchmark_value"`
+	PortfolioReturn float64            `json:"portfolio_return"`
+	BenchmarkReturn float64            `json:"benchmark_return"`
+	RelativeReturn  float64            `json:"relative_return"`
+	Outperformed    bool               `json:"outperformed"`
+	Curve           []EquityPoint      `json:"curve"`
+}
+
+// ParseBenchmark - benchmark from "BTC" or a weighted basket like "BTC:60,ETH:40"
+func ParseBenchmark(DB *sql.DB, value string) ([]AllocationTarget, error) {
+	benchmark := []AllocationTarget{}
+
+	for _, part := range strings.Split(value, ",") {
+		component := AllocationTarget{Symbol: strings.TrimSpace(part), Weight: 100}
+
+		if separator := strings.Index(part, ":"); separator >= 0 {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(part[separator+1:]), 64)
+
+			if err != nil {
+				return nil, errors.New("Benchmark weights have to be numbers.")
+			}
+
+			component = AllocationTarget{Symbol: strings.TrimSpace(part[:separator]), Weight: weight}
+		}
+
+		benchmark = append(benchmark, component)
+	}
+
+	if err := ValidateAllocationTargets(DB, benchmark); err != nil {
+		return nil, err
+	}
+
+	return benchmark, nil
+}
+
+// CompareWithBenchmark - replay portfolio cash flows into the benchmark and compare both equity curves over the period
+// Money put in buys the benchmark coins by weight at that day's price, money taken out sells them in proportion to their worth.
+func CompareWithBenchmark(DB *sql.DB, userID int, portfolioID int, benchmark []AllocationTarget, period string) (BenchmarkComparison, error) {
+	comparison := BenchmarkComparison{Benchmark: benchmark, Period: period, Curve: []EquityPoint{}}
+
+	points := portfolioValuePoints(GetUserSnapshots(DB, userID, portfolioID), GetUserTransactions(DB, userID, portfolioID))
+
+	if len(points) == 0 {
+		return comparison, errors.New("There are no snapshots of this portfolio yet.")
+	}
+
+	from := PerformancePeriodStart(period, time.Now())
+
+	base := 0
+
+	for i, point := range points {
+		if point.Date.After(from) {
+			break
+		}
+
+		base = i
+	}
+
+	points = points[base:]
+
+	componentIDs := []int{}
+
+	for _, component := range benchmark {
+		componentIDs = append(componentIDs, GetCoinIDBySymbol(DB, component.Symbol))
+	}
+
+	// Comparison starts on the first day all benchmark coins have price history
+	historyFrom := clampToHistory(DB, componentIDs, points[0].Date)
+
+	for len(points) > 0 && points[0].Date.Before(historyFrom) {
+		points = points[1:]
+	}
+
+	if len(points) == 0 {
+		return comparison, errors.New("There is no price history for the benchmark in this period yet.")
+	}
+
+	days := []time.Time{}
+
+	for _, point := range points {
+		days = append(days, point.Date)
+	}
+
+	prices := [][]float64{}
+
+	for i, component := range benchmark {
+		componentPrices, ok := dailyCoinPrices(DB, componentIDs[i], days)
+
+		if !ok {
+			return comparison, errors.New("There is no price history for " + component.Symbol + " yet.")
+		}
+
+		prices = append(prices, componentPrices)
+	}
+
+	units := make([]float64, len(benchmark))
+	benchmarkPoints := []valuePoint{}
+
+	for i, point := range points {
+		// Starting value goes into the benchmark on the first day, later days get the portfolio's cash flows
+		flow := point.Flow
+
+		if i == 0 {
+			flow = point.Value
+		}
+
+		worth := 0.0
+
+		for j := range benchmark {
+			worth += units[j] * prices[j][i]
+		}
+
+		for j, component := range benchmark {
+			if prices[j][i] <= 0 {
+				continue
+			}
+
+			if flow >= 0 {
+				units[j] += flow * component.Weight / 100 / prices[j][i]
+			} else if worth > 0 {
+				units[j] += units[j] * flow / worth
+			}
+		}
+
+		value := 0.0
+
+		for j := range benchmark {
+			value += units[j] * prices[j][i]
+		}
+
+		benchmarkPoint := valuePoint{Date: point.Date, Value: value, Flow: point.Flow}
+
+		if i == 0 {
+			benchmarkPoint.Flow = 0
+		}
+
+		benchmarkPoints = append(benchmarkPoints, benchmarkPoint)
+
+		comparison.Curve = append(comparison.Curve, EquityPoint{
+			Date:      point.Date.Format(SnapshotDateFormat),
+			Portfolio: roundNumber(point.Value),
+			Benchmark: roundNumber(value),
+			Flow:      roundNumber(benchmarkPoint.Flow),
+		})
+	}
+
+	last := len(points) - 1
+
+	comparison.From = comparison.Curve[0].Date
+	comparison.To = comparison.Curve[last].Date
+	comparison.PortfolioValue = comparison.Curve[last].Portfolio
+	comparison.BenchmarkValue = comparison.Curve[last].Benchmark
+	comparison.PortfolioReturn = roundNumber(timeWeightedReturn(points) * 100)
+	comparison.BenchmarkReturn = roundNumber(timeWeightedReturn(benchmarkPoints) * 100)
+	comparison.RelativeReturn = roundNumber(comparison.PortfolioReturn - comparison.BenchmarkReturn)
+	comparison.Outperformed = comparison.RelativeReturn > 0
+
+	return comparison, nil
+}
+
+// ConvertBenchmarkComparison - convert EUR values of both equity curves into the currency
+// Returns are left as they are, one rate moves every value by the same factor.
+func ConvertBenchmarkComparison(DB *sql.DB, comparison BenchmarkComparison, currency string) (BenchmarkComparison, string) {
+	rate, currency := ConversionRate(DB, currency)
+
+	if rate == 1 {
+		return comparison, currency
+	}
+
+	comparison.PortfolioValue = roundNumber(comparison.PortfolioValue * rate)
+	comparison.BenchmarkValue = roundNumber(comparison.BenchmarkValue * rate)
+
+	curve := make([]EquityPoint, len(comparison.Curve))
+
+	for i, point := range comparison.Curve {
+		point.Portfolio = roundNumber(point.Portfolio * rate)
+		point.Benchmark = roundNumber(point.Benchmark * rate)
+		point.Flow = roundNumber(point.Flow * rate)
+
+		curve[i] = point
+	}
+
+	comparison.Curve = curve
+
+	return comparison, currency
+}
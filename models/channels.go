@@ -0,0 +1,434 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// ChannelTypes - supported chat platforms, each posts to its own incoming-webhook style URL
+// Slack and Discord use the webhook URL from the workspace; Telegram uses the bot's sendMessage URL
+// (https://api.telegram.org/bot<token>/sendMessage) together with ChatID.
+var ChannelTypes = []string{"slack", "discord", "telegram"}
+
+// ChannelMaxAttempts - failed messages are retried with exponential backoff up to this many attempts
+const ChannelMaxAttempts = 5
+
+// NotificationChannel - chat channel registered by the user
+type NotificationChannel struct {
+	ChannelID int    `json:"channelid"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	ChatID    string `json:"chat_id"`
+	Enabled   bool   `json:"enabled"`
+	DateAdded string `json:"date_added"`
+}
+
+// ChannelMessage - message posted (or to be posted) to a channel
+type ChannelMessage struct {
+	ChannelMessageID int    `json:"channelmessageid"`
+	ChannelID        int    `json:"channelid"`
+	Event            string `json:"event"`
+	Text             string `json:"text"`
+	Status           string `json:"status"`
+	Attempts         int    `json:"attempts"`
+	StatusCode       int    `json:"status_code"`
+	LastError        string `json:"last_error"`
+	NextAttempt      string `json:"next_attempt"`
+	DateAdded        string `json:"date_added"`
+	DateSent         string `json:"date_sent"`
+}
+
+// channelColumns - channel columns in the order they are scanned by scanChannel
+const channelColumns = "channelid, type, name, url, chat_id, enabled, date_added"
+
+// channelMessageColumns - message columns in the order they are scanned by scanChannelMessage
+const channelMessageColumns = "channelmessageid, channelid, event, text, status, attempts, status_code, last_error, next_attempt, date_added, date_sent"
+
+// ValidateChannel - check channel type, URL and Telegram chat
+func ValidateChannel(channel NotificationChannel) error {
+	if indexOf(ChannelTypes, channel.Type) < 0 {
+		return errors.New("Channel type has to be one of: " + strings.Join(ChannelTypes, ", ") + ".")
+	}
+
+	if channel.Name == "" {
+		return errors.New("Please provide channel name.")
+	}
+
+	if err := ValidateOutboundURL(channel.URL); err != nil {
+		return err
+	}
+
+	if channel.Type == "telegram" && channel.ChatID == "" {
+		return errors.New("Please provide Telegram chat ID.")
+	}
+
+	return nil
+}
+
+// CreateChannel - add new notification channel
+func CreateChannel(DB *sql.DB, userID int, channel NotificationChannel) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO notificationchannels(userid, type, name, url, chat_id, enabled, date_added) VALUES($1, $2, $3, $4, $5, $6, $7) returning channelid;",
+		userID, channel.Type, channel.Name, channel.URL, channel.ChatID, channel.Enabled, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// UpdateChannel - update notification channel
+func UpdateChannel(DB *sql.DB, userID int, channel NotificationChannel) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE notificationchannels SET type = $1, name = $2, url = $3, chat_id = $4, enabled = $5 WHERE channelid = $6 AND userid = $7 returning channelid;",
+		channel.Type, channel.Name, channel.URL, channel.ChatID, channel.Enabled, channel.ChannelID, userID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// RemoveChannel - delete channel, its messages and detach it from alerts and digest
+func RemoveChannel(DB *sql.DB, userID int, channelID int) bool {
+	result, err := DB.Exec("DELETE FROM notificationchannels WHERE channelid = $1 AND userid = $2", channelID, userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if removed, _ := result.RowsAffected(); removed < 1 {
+		return false
+	}
+
+	for _, query := range []string{
+		"DELETE FROM channelmessages WHERE channelid = $1 AND userid = $2",
+		"UPDATE alerts SET channelid = 0 WHERE channelid = $1 AND userid = $2",
+	} {
+		if _, err = DB.Exec(query, channelID, userID); err != nil {
+			panic(err)
+		}
+	}
+
+	if GetUserSetting(DB, userID, "digest_channelid") == strconv.Itoa(channelID) {
+		SetUserSetting(DB, userID, "digest_channelid", "0")
+	}
+
+	return true
+}
+
+// GetUserChannel - get user's channel, ChannelID is 0 if it does not belong to the user
+func GetUserChannel(DB *sql.DB, userID int, channelID int) NotificationChannel {
+	var channel NotificationChannel
+
+	err := scanChannel(DB.QueryRow("SELECT "+channelColumns+" FROM notificationchannels WHERE channelid = $1 AND userid = $2", channelID, userID), &channel)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return channel
+}
+
+// GetUserChannels - get all channels of the user
+func GetUserChannels(DB *sql.DB, userID int) []NotificationChannel {
+	rows, err := DB.Query("SELECT "+channelColumns+" FROM notificationchannels WHERE userid = $1 ORDER BY channelid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var channels []NotificationChannel
+
+	// Foreach channel
+	for rows.Next() {
+		var channel NotificationChannel
+
+		if err = scanChannel(rows, &channel); err != nil {
+			panic(err)
+		}
+
+		channels = append(channels, channel)
+	}
+
+	return channels
+}
+
+// GetChannelMessages - latest messages of the channel
+func GetChannelMessages(DB *sql.DB, channelID int, limit int) []ChannelMessage {
+	rows, err := DB.Query("SELECT "+channelMessageColumns+" FROM channelmessages WHERE channelid = $1 ORDER BY channelmessageid DESC LIMIT $2", channelID, limit)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var messages []ChannelMessage
+
+	// Foreach message
+	for rows.Next() {
+		var message ChannelMessage
+
+		if err = scanChannelMessage(rows, &message); err != nil {
+			panic(err)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// QueueChannelMessage - format event for chat and store it, it is sent by SendChannelMessages
+// Disabled channels and events without a chat format are skipped.
+func QueueChannelMessage(DB *sql.DB, userID int, channelID int, event string, data interface{}) int {
+	return createChannelMessage(DB, userID, channelID, event, data, "pending")
+}
+
+// SendChannelTest - post test message to the channel right away, it is not queued and not retried
+func SendChannelTest(DB *sql.DB, userID int, channelID int) ChannelMessage {
+	return SendChannelMessage(DB, createChannelMessage(DB, userID, channelID, "channel.test", nil, "sending"))
+}
+
+// createChannelMessage - store formatted message with the status, only pending ones are picked up by SendChannelMessages
+func createChannelMessage(DB *sql.DB, userID int, channelID int, event string, data interface{}, status string) int {
+	channel := GetUserChannel(DB, userID, channelID)
+
+	if channel.ChannelID < 1 || !channel.Enabled {
+		return 0
+	}
+
+	text := FormatChannelMessage(channel.Type, event, data)
+
+	if text == "" {
+		return 0
+	}
+
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO channelmessages(channelid, userid, event, text, status, next_attempt, date_added) VALUES($1, $2, $3, $4, $5, $6, $7) returning channelmessageid;",
+		channel.ChannelID, userID, event, text, status, helpers.GetCurrentDateTime(), helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// SendChannelMessages - post pending channel messages that are due
+func SendChannelMessages(minutes int, DB *sql.DB) {
+	d := time.Duration(minutes) * time.Minute
+
+	for range time.Tick(d) {
+		rows, err := DB.Query("SELECT channelmessageid FROM channelmessages WHERE status = $1 AND next_attempt <= $2 ORDER BY channelmessageid", "pending", helpers.GetCurrentDateTime())
+
+		if err != nil {
+			panic(err)
+		}
+
+		var messageIDs []int
+
+		// Foreach message
+		for rows.Next() {
+			var messageID int
+
+			if err = rows.Scan(&messageID); err != nil {
+				panic(err)
+			}
+
+			messageIDs = append(messageIDs, messageID)
+		}
+
+		rows.Close()
+
+		for _, messageID := range messageIDs {
+			SendChannelMessage(DB, messageID)
+		}
+	}
+}
+
+// SendChannelMessage - post single message and store the outcome
+// Failed pending messages are retried after 1, 2, 4... minutes until ChannelMaxAttempts is reached,
+// test messages fail right away.
+func SendChannelMessage(DB *sql.DB, messageID int) ChannelMessage {
+	var message ChannelMessage
+	var channel NotificationChannel
+
+	err := DB.QueryRow("SELECT m.channelmessageid, m.channelid, m.event, m.text, m.status, m.attempts, m.status_code, m.last_error, m.next_attempt, m.date_added, m.date_sent, c.type, c.url, c.chat_id FROM channelmessages m INNER JOIN notificationchannels c ON c.channelid = m.channelid WHERE m.channelmessageid = $1", messageID).Scan(
+		&message.ChannelMessageID, &message.ChannelID, &message.Event, &message.Text, &message.Status, &message.Attempts, &message.StatusCode,
+		&message.LastError, &message.NextAttempt, &message.DateAdded, &message.DateSent, &channel.Type, &channel.URL, &channel.ChatID)
+
+	if err == sql.ErrNoRows {
+		return message
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	retry := message.Status == "pending"
+
+	message.Attempts++
+	message.StatusCode, message.LastError = postChannelMessage(channel, message.Text)
+
+	if message.LastError == "" {
+		message.Status = "sent"
+		message.DateSent = helpers.GetCurrentDateTime()
+	} else if !retry || message.Attempts >= ChannelMaxAttempts {
+		message.Status = "failed"
+	} else {
+		backoff := time.Duration(math.Pow(2, float64(message.Attempts-1))) * time.Minute
+		message.NextAttempt = helpers.FormatDateTime(time.Now().Add(backoff))
+	}
+
+	_, err = DB.Exec("UPDATE channelmessages SET status = $1, attempts = $2, status_code = $3, last_error = $4, next_attempt = $5, date_sent = $6 WHERE channelmessageid = $7",
+		message.Status, message.Attempts, message.StatusCode, message.LastError, message.NextAttempt, message.DateSent, message.ChannelMessageID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return message
+}
+
+// FormatChannelMessage - chat text of the event, empty if the event is not posted to chats
+func FormatChannelMessage(channelType string, event string, data interface{}) string {
+	bold := func(text string) string {
+		if channelType == "discord" {
+			return "**" + text + "**"
+		}
+
+		return "*" + text + "*"
+	}
+
+	var lines []string
+
+	switch data := data.(type) {
+	case AlertFiredEvent:
+		title := "Alert"
+
+		if data.Alert.Symbol != "" {
+			title = data.Alert.Symbol + " alert"
+		}
+
+		lines = append(lines, bold(title)+": "+data.Message)
+	case Digest:
+		lines = append(lines,
+			bold("Your "+data.Frequency+" portfolio digest"),
+			fmt.Sprintf("Worth: %.2f EUR (%+.2f EUR, %+.2f%%)", data.Worth, data.WorthChange, data.WorthChangePercent),
+			fmt.Sprintf("Profit: %.2f EUR (%+.2f EUR)", data.Profit, data.ProfitChange),
+		)
+
+		for _, group := range []struct {
+			Title  string
+			Movers []DigestMover
+		}{
+			{"Top gainers", data.Gainers},
+			{"Top losers", data.Losers},
+		} {
+			if len(group.Movers) == 0 {
+				continue
+			}
+
+			lines = append(lines, bold(group.Title))
+
+			for _, mover := range group.Movers {
+				lines = append(lines, fmt.Sprintf("- %s: %+.2f%% (%+.2f EUR)", mover.Symbol, mover.ChangePercent, mover.Change))
+			}
+		}
+
+		if len(data.Alerts) > 0 {
+			lines = append(lines, bold("Alerts"))
+
+			for _, firing := range data.Alerts {
+				lines = append(lines, "- "+firing.Message)
+			}
+		}
+	default:
+		if event == "channel.test" {
+			lines = append(lines, bold(helpers.GetConfig().APPName)+": this is a test message.")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// postChannelMessage - POST text in the platform's format, returns status code and error message (empty on 2xx)
+func postChannelMessage(channel NotificationChannel, text string) (int, string) {
+	var body interface{}
+
+	switch channel.Type {
+	case "slack":
+		body = map[string]string{"text": text}
+	case "discord":
+		body = map[string]string{"content": text}
+	case "telegram":
+		body = map[string]string{"chat_id": channel.ChatID, "text": text, "parse_mode": "Markdown"}
+	default:
+		return 0, "Unknown channel type " + channel.Type + "."
+	}
+
+	payload, err := json.Marshal(body)
+
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	request, err := http.NewRequest("POST", channel.URL, bytes.NewReader(payload))
+
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	request.Header.Set("User-Agent", helpers.GetConfig().APPName+" Notifications")
+
+	response, err := webhookClient.Do(request)
+
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	defer response.Body.Close()
+
+	ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, "Channel responded with status " + strconv.Itoa(response.StatusCode) + "."
+	}
+
+	return response.StatusCode, ""
+}
+
+// scanChannel - scan channelColumns into channel
+func scanChannel(row interface{ Scan(...interface{}) error }, channel *NotificationChannel) error {
+	return row.Scan(&channel.ChannelID, &channel.Type, &channel.Name, &channel.URL, &channel.ChatID, &channel.Enabled, &channel.DateAdded)
+}
+
+// scanChannelMessage - scan channelMessageColumns into message
+func scanChannelMessage(row interface{ Scan(...interface{}) error }, message *ChannelMessage) error {
+	return row.Scan(&message.ChannelMessageID, &message.ChannelID, &message.Event, &message.Text, &message.Status, &message.Attempts,
+		&message.StatusCode, &message.LastError, &message.NextAttempt, &message.DateAdded, &message.DateSent)
+}
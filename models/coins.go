@@ -3,43 +3,65 @@ package models
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"math"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/karolispx/golang-crypto-portfolio/helpers"
 	_ "github.com/lib/pq"
 )
 
+// CoinFromCMC - single coin from coinmarketcap
+type CoinFromCMC struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+	Quote  struct {
+		EUR struct {
+			Price            float64 `json:"price"`
+			PercentChange24h float64 `json:"percent_change_24h"`
+		} `json:"EUR"`
+	} `json:"quote"`
+}
+
 // CoinInfoFromCMC - coin info from coinmarketcap
 type CoinInfoFromCMC struct {
-	Data []struct {
-		ID     int    `json:"id"`
-		Name   string `json:"name"`
-		Symbol string `json:"symbol"`
-		Quote  struct {
-			EUR struct {
-				Price float64 `json:"price"`
-			} `json:"EUR"`
-		} `json:"quote"`
-	} `json:"data"`
+	Data []CoinFromCMC `json:"data"`
+}
+
+// CoinQuotesFromCMC - quotes of selected coins from coinmarketcap, keyed by CMC ID
+type CoinQuotesFromCMC struct {
+	Data map[string]CoinFromCMC `json:"data"`
 }
 
 // Coin struct - store coin's info
 type Coin struct {
-	UserCoinID  int     `json:"coinid"`
-	Name        string  `json:"name"`
-	Symbol      string  `json:"symbol"`
-	Invested    float64 `json:"invested"`
-	Amount      float64 `json:"amount"`
-	MadeLost    float64 `json:"madelost"`
-	Worth       float64 `json:"worth"`
-	PriceEur    float64 `json:"priceeur"`
-	Lives       string  `json:"lives"`
-	DateAdded   string  `json:"date_added"`
-	DateUpdated string  `json:"date_updated"`
+	UserCoinID  int      `json:"coinid"`
+	PortfolioID int      `json:"portfolioid"`
+	Name        string   `json:"name"`
+	Symbol      string   `json:"symbol"`
+	Invested    float64  `json:"invested"`
+	Amount      float64  `json:"amount"`
+	MadeLost    float64  `json:"madelost"`
+	Worth       float64  `json:"worth"`
+	PriceEur    float64  `json:"priceeur"`
+	Change24h   float64  `json:"percent_change_24h"`
+	Lives       string   `json:"lives"`
+	DateAdded   string   `json:"date_added"`
+	DateUpdated string   `json:"date_updated"`
+	AvgBuyPrice float64  `json:"avg_buy_price"`
+	BreakEven   float64  `json:"break_even_price"`
+	TakeProfit  *Target  `json:"take_profit"`
+	StopLoss    *Target  `json:"stop_loss"`
+	Tags        []string `json:"tags"`
+	Notes       string   `json:"notes"`
 }
 
 // SyncInfo - store info about the sync
@@ -50,8 +72,23 @@ type SyncInfo struct {
 	LastSync string  `json:"last_sync"`
 }
 
+// SyncCompletedEvent - data sent to webhooks after user's coins were synced
+type SyncCompletedEvent struct {
+	CoinData []Coin   `json:"coindata"`
+	SyncData SyncInfo `json:"syncdata"`
+}
+
+// HoldingChangedEvent - data sent to webhooks when user adds, edits, deletes or imports coins
+type HoldingChangedEvent struct {
+	Action string `json:"action"`
+	CoinID int    `json:"coinid"`
+	Symbol string `json:"symbol"`
+	Count  int    `json:"count"`
+}
+
 // CoinSymbolInfo - coin symbol info
 type CoinSymbolInfo struct {
+	CoinID int    `json:"coinid"`
 	Name   string `json:"name"`
 	Symbol string `json:"symbol"`
 }
@@ -61,73 +98,168 @@ func UpdateCoinsFromCMC(minutes int, DB *sql.DB) {
 	d := time.Duration(minutes) * time.Minute
 	rand.Seed(time.Now().Unix())
 
+	var lastPriceHistory time.Time
+
 	for range time.Tick(d) {
-		request, err := http.NewRequest("GET", "https://pro-api.coinmarketcap.com/v1/cryptocurrency/listings/latest?limit=5000&convert=EUR", nil)
+		items := CoinInfoFromCMC{}
 
-		if err != nil {
-			panic(err)
+		if err := getFromCMC("/v1/cryptocurrency/listings/latest?limit=5000&convert=EUR", &items); err != nil {
+			log.Printf("Coins could not be updated from CMC: %s", err)
+
+			continue
 		}
 
-		Config := helpers.GetConfig()
+		// Prices are kept in history at most once per PriceHistoryInterval
+		recordPriceHistory := time.Since(lastPriceHistory) >= PriceHistoryInterval
 
-		randomNumberForCMCAPIKey := rand.Intn(9-0) + 0
+		if recordPriceHistory {
+			lastPriceHistory = time.Now()
+		}
 
-		request.Header.Set("X-Cmc_pro_api_key", Config.CMCAPIKeys[randomNumberForCMCAPIKey].APIKey)
+		var historyCoinIDs map[int]bool
 
-		response, err := http.DefaultClient.Do(request)
+		if recordPriceHistory {
+			historyCoinIDs = GetPriceHistoryCoinIDs(DB)
+		}
 
-		if err != nil {
-			panic(err)
+		// Listings are sorted by market cap
+		for i, item := range items.Data {
+			coinID := saveCoinFromCMC(DB, item)
+
+			if recordPriceHistory && (i < PriceHistoryTopCoins || historyCoinIDs[coinID]) {
+				RecordCoinPrice(DB, coinID, item.Quote.EUR.Price)
+			}
 		}
 
-		defer response.Body.Close()
+		// Check price alerts against the new prices
+		CheckAlerts(DB)
+	}
+}
 
-		items := CoinInfoFromCMC{}
+// UpdateTrackedCoinsFromCMC - refresh quotes of coins held or watched by users between full syncs
+func UpdateTrackedCoinsFromCMC(minutes int, DB *sql.DB) {
+	d := time.Duration(minutes) * time.Minute
 
-		body, err := ioutil.ReadAll(response.Body)
+	for range time.Tick(d) {
+		cmcIDs := GetTrackedCMCIDs(DB)
 
-		if err != nil {
-			panic(err)
+		if len(cmcIDs) == 0 {
+			continue
 		}
 
-		err = json.Unmarshal(body, &items)
+		items := CoinQuotesFromCMC{}
 
-		if err != nil {
-			panic(err)
+		if err := getFromCMC("/v1/cryptocurrency/quotes/latest?convert=EUR&id="+strings.Join(cmcIDs, ","), &items); err != nil {
+			log.Printf("Tracked coins could not be updated from CMC: %s", err)
+
+			continue
 		}
 
 		for _, item := range items.Data {
-			count := 0
+			saveCoinFromCMC(DB, item)
+		}
 
-			row := DB.QueryRow("SELECT COUNT(*) FROM coins where cmcid = $1", item.ID)
+		// Check price alerts against the new prices
+		CheckAlerts(DB)
+	}
+}
 
-			err := row.Scan(&count)
+// trackedCoinsCondition - coins c that are in users' portfolios or watchlists
+const trackedCoinsCondition = "c.symbol IN (SELECT symbol FROM usercoins) OR c.coinid IN (SELECT coinid FROM watchlist)"
 
-			if err != nil {
-				panic(err)
-			}
+// GetTrackedCMCIDs - CMC IDs of coins in users' portfolios and watchlists
+func GetTrackedCMCIDs(DB *sql.DB) []string {
+	rows, err := DB.Query("SELECT DISTINCT c.cmcid FROM coins c WHERE " + trackedCoinsCondition + " ORDER BY c.cmcid")
 
-			if count < 1 {
-				// Insert new coin into database
-				lastInsertID := 0
-				err = DB.QueryRow("INSERT INTO coins(cmcid, name, symbol, priceeur) VALUES($1, $2, $3, $4) returning coinid;", item.ID, item.Name, item.Symbol, item.Quote.EUR.Price).Scan(&lastInsertID)
+	if err != nil {
+		panic(err)
+	}
 
-				if err != nil {
-					panic(err)
-				}
-			} else {
-				// Update existing coin in the database
-				lastUpdatedID := 0
+	defer rows.Close()
 
-				err = DB.QueryRow("UPDATE coins SET name = $1, symbol = $2, priceeur = $3 where cmcid = $4 returning coinid;",
-					item.Name, item.Symbol, item.Quote.EUR.Price, item.ID).Scan(&lastUpdatedID)
+	var cmcIDs []string
 
-				if err != nil {
-					panic(err)
-				}
-			}
+	// Foreach coin
+	for rows.Next() {
+		var cmcID int
+
+		if err = rows.Scan(&cmcID); err != nil {
+			panic(err)
 		}
+
+		cmcIDs = append(cmcIDs, strconv.Itoa(cmcID))
+	}
+
+	return cmcIDs
+}
+
+// cmcClient - HTTP client used for coinmarketcap requests
+var cmcClient = &http.Client{Timeout: 30 * time.Second}
+
+// getFromCMC - GET coinmarketcap API path with one of the API keys from config.json picked at random and decode the JSON response
+// Network errors and error responses are returned, so a failed sync is skipped instead of stopping the server.
+func getFromCMC(path string, result interface{}) error {
+	request, err := http.NewRequest("GET", "https://pro-api.coinmarketcap.com"+path, nil)
+
+	if err != nil {
+		return err
+	}
+
+	Config := helpers.GetConfig()
+
+	if len(Config.CMCAPIKeys) == 0 {
+		return errors.New("there are no CMC API keys in config.json")
 	}
+
+	randomNumberForCMCAPIKey := rand.Intn(len(Config.CMCAPIKeys))
+
+	request.Header.Set("X-Cmc_pro_api_key", Config.CMCAPIKeys[randomNumberForCMCAPIKey].APIKey)
+
+	response, err := cmcClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("CMC responded with status %d", response.StatusCode)
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+// saveCoinFromCMC - insert or update coin from coinmarketcap, returns coin ID
+func saveCoinFromCMC(DB *sql.DB, item CoinFromCMC) int {
+	coinID := 0
+
+	err := DB.QueryRow("UPDATE coins SET name = $1, symbol = $2, priceeur = $3, percent_change_24h = $4 where cmcid = $5 returning coinid;",
+		item.Name, item.Symbol, item.Quote.EUR.Price, item.Quote.EUR.PercentChange24h, item.ID).Scan(&coinID)
+
+	if err == nil {
+		return coinID
+	}
+
+	if err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	// Insert new coin into database
+	err = DB.QueryRow("INSERT INTO coins(cmcid, name, symbol, priceeur, percent_change_24h) VALUES($1, $2, $3, $4, $5) returning coinid;",
+		item.ID, item.Name, item.Symbol, item.Quote.EUR.Price, item.Quote.EUR.PercentChange24h).Scan(&coinID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return coinID
 }
 
 // UpdateUsersCoins - update coins for all users
@@ -157,48 +289,46 @@ func UpdateUsersCoins(minutes int, DB *sql.DB) {
 				panic(err)
 			}
 
-			_ = UpdateUserCoins(UserID, DB)
-		}
-	}
-}
+			coins, syncInfo := GetUserCoins(UserID, AllPortfolios, DB)
 
-// UpdateUserCoins - update user coins
-func UpdateUserCoins(userID int, DB *sql.DB) []Coin {
-	// Get all coins for this user
-	rows, err := DB.Query("SELECT * FROM usercoins WHERE userid = $1", userID)
+			// First sync of the day closes yesterday's snapshot
+			if GetUserSnapshot(DB, UserID, AllPortfolios, time.Now().Format(SnapshotDateFormat)).SnapshotID < 1 {
+				yesterday := GetUserSnapshot(DB, UserID, AllPortfolios, time.Now().AddDate(0, 0, -1).Format(SnapshotDateFormat))
 
-	if err != nil {
-		panic(err)
+				if yesterday.SnapshotID > 0 {
+					NotifyUser(DB, UserID, "daily.summary", yesterday)
+				}
+			}
+
+			RecordSnapshot(DB, UserID, AllPortfolios, syncInfo)
+
+			// Every portfolio keeps its own history next to the combined one
+			for _, portfolio := range GetUserPortfolios(DB, UserID) {
+				_, portfolioSyncInfo := GetUserCoins(UserID, portfolio.PortfolioID, DB)
+
+				RecordSnapshot(DB, UserID, portfolio.PortfolioID, portfolioSyncInfo)
+			}
+
+			CheckPortfolioAlerts(DB, UserID, syncInfo)
+
+			NotifyUser(DB, UserID, "sync.completed", SyncCompletedEvent{CoinData: coins, SyncData: syncInfo})
+		}
 	}
+}
 
+// UpdateUserCoins - update user coins with the latest prices, AllPortfolios updates every portfolio
+func UpdateUserCoins(userID int, portfolioID int, DB *sql.DB) []Coin {
 	var coins []Coin
 
 	// Foreach coin
-	for rows.Next() {
-		var UserCoinID int
-		var UserID int
-		var Name string
-		var Symbol string
-		var Invested float64
-		var Amount float64
-		var MadeLost float64
-		var Worth float64
-		var PriceEur float64
-		var Lives string
-		var DateAdded string
-		var DateUpdated string
-
-		err = rows.Scan(&UserCoinID, &UserID, &Name, &Symbol, &Invested, &Amount, &MadeLost, &Worth, &PriceEur, &Lives, &DateAdded, &DateUpdated)
+	for _, coin := range GetUserHoldings(DB, userID, portfolioID) {
+		Invested := roundNumber(coin.Invested)
 
-		if err != nil {
-			panic(err)
-		}
-
-		Invested = math.Round(Invested*100) / 100
-		Amount = math.Round(Amount*100) / 100
+		// Amounts are kept as they are, small holdings would lose most of their value and unit prices when rounded
+		Amount := coin.Amount
 
 		// Get coin info for this coin name and symbol
-		rows, err := DB.Query("SELECT * FROM coins WHERE name = $1 AND symbol = $2", Name, Symbol)
+		rows, err := DB.Query("SELECT coinid, cmcid, name, symbol, priceeur, percent_change_24h FROM coins WHERE name = $1 AND symbol = $2", coin.Name, coin.Symbol)
 
 		if err != nil {
 			panic(err)
@@ -211,8 +341,9 @@ func UpdateUserCoins(userID int, DB *sql.DB) []Coin {
 			var CoinName string
 			var CoinSymbol string
 			var CoinPriceEur float64
+			var CoinChange24h float64
 
-			err = rows.Scan(&CoinID, &CMCCoinID, &CoinName, &CoinSymbol, &CoinPriceEur)
+			err = rows.Scan(&CoinID, &CMCCoinID, &CoinName, &CoinSymbol, &CoinPriceEur, &CoinChange24h)
 
 			if err != nil {
 				panic(err)
@@ -223,43 +354,69 @@ func UpdateUserCoins(userID int, DB *sql.DB) []Coin {
 			CoinWorth := calculatedPrice
 			CoinMadeLost := calculatedPrice - Invested
 
-			CoinWorth = math.Round(CoinWorth*100) / 100
-			CoinMadeLost = math.Round(CoinMadeLost*100) / 100
+			CoinWorth = roundNumber(CoinWorth)
+			CoinMadeLost = roundNumber(CoinMadeLost)
 
 			// Update user coin with coin info
 			var lastUpdatedID int
 
-			err = DB.QueryRow("UPDATE usercoins SET date_updated = $1, Worth = $2, MadeLost = $3, PriceEur = $4 where userid = $5 AND name = $6 AND symbol = $7 returning usercoinid;",
-				helpers.GetCurrentDateTime(), CoinWorth, CoinMadeLost, CoinPriceEur, userID, CoinName, CoinSymbol).Scan(&lastUpdatedID)
+			err = DB.QueryRow("UPDATE usercoins SET date_updated = $1, Worth = $2, MadeLost = $3, PriceEur = $4 where usercoinid = $5 returning usercoinid;",
+				helpers.GetCurrentDateTime(), CoinWorth, CoinMadeLost, CoinPriceEur, coin.UserCoinID).Scan(&lastUpdatedID)
 
 			if err != nil {
 				panic(err)
 			}
 
-			coins = append(coins, Coin{
-				UserCoinID:  UserCoinID,
-				Name:        Name,
-				Symbol:      Symbol,
+			updatedCoin := Coin{
+				UserCoinID:  coin.UserCoinID,
+				PortfolioID: coin.PortfolioID,
+				Name:        coin.Name,
+				Symbol:      coin.Symbol,
 				Invested:    Invested,
 				Amount:      Amount,
 				MadeLost:    CoinMadeLost,
 				Worth:       CoinWorth,
-				PriceEur:    PriceEur,
-				Lives:       Lives,
-				DateAdded:   DateAdded,
-				DateUpdated: DateUpdated,
-			})
+				PriceEur:    coin.PriceEur,
+				Change24h:   CoinChange24h,
+				Lives:       coin.Lives,
+				DateAdded:   coin.DateAdded,
+				DateUpdated: coin.DateUpdated,
+				TakeProfit:  coin.TakeProfit,
+				StopLoss:    coin.StopLoss,
+				Notes:       coin.Notes,
+			}
+
+			setUnitFigures(DB, &updatedCoin, CoinPriceEur)
+
+			coins = append(coins, updatedCoin)
 		}
+
+		rows.Close()
 	}
 
 	return coins
 }
 
-// GetUserCoins - get all user coins
-func GetUserCoins(userID int, DB *sql.DB) ([]Coin, SyncInfo) {
+// GetUserCoins - get user coins of the portfolio with totals, AllPortfolios combines every portfolio
+func GetUserCoins(userID int, portfolioID int, DB *sql.DB) ([]Coin, SyncInfo) {
 	// Update user coins
-	coins := UpdateUserCoins(userID, DB)
+	coins := UpdateUserCoins(userID, portfolioID, DB)
+
+	holdingTags := GetHoldingTags(DB, userID)
 
+	for i := range coins {
+		coins[i].Tags = holdingTags[coins[i].UserCoinID]
+
+		if coins[i].Tags == nil {
+			coins[i].Tags = []string{}
+		}
+	}
+
+	return coins, CalculateSyncInfo(coins)
+}
+
+// CalculateSyncInfo - totals of the coins
+func CalculateSyncInfo(coins []Coin) SyncInfo {
 	var syncInfo SyncInfo
 
 	syncInfo.LastSync = helpers.GetCurrentDateTime()
@@ -274,18 +431,23 @@ func GetUserCoins(userID int, DB *sql.DB) ([]Coin, SyncInfo) {
 
 	syncInfo.Profit = syncInfo.Worth - syncInfo.Invested
 
-	syncInfo.Invested = math.Round(syncInfo.Invested*100) / 100
-	syncInfo.Worth = math.Round(syncInfo.Worth*100) / 100
-	syncInfo.Profit = math.Round(syncInfo.Profit*100) / 100
+	syncInfo.Invested = roundNumber(syncInfo.Invested)
+	syncInfo.Worth = roundNumber(syncInfo.Worth)
+	syncInfo.Profit = roundNumber(syncInfo.Profit)
+
+	return syncInfo
+}
 
-	return coins, syncInfo
+// roundNumber - round money, prices and percentages in responses to 2 decimals
+func roundNumber(value float64) float64 {
+	return math.Round(value*100) / 100
 }
 
 // GetCoins - get all coins
 func GetCoins(DB *sql.DB) []CoinSymbolInfo {
 
 	// Get all coins
-	rows, err := DB.Query("SELECT * FROM coins")
+	rows, err := DB.Query("SELECT coinid, cmcid, name, symbol, priceeur FROM coins")
 
 	if err != nil {
 		panic(err)
@@ -308,6 +470,7 @@ func GetCoins(DB *sql.DB) []CoinSymbolInfo {
 		}
 
 		coins = append(coins, CoinSymbolInfo{
+			CoinID: CoinID,
 			Name:   CoinName,
 			Symbol: CoinSymbol,
 		})
@@ -317,33 +480,25 @@ func GetCoins(DB *sql.DB) []CoinSymbolInfo {
 }
 
 // GetCoinBySymbol -
-func GetCoinBySymbol(DB *sql.DB, coinSymbol string) (coinNameReturn string, coinPriceEURReturn float64) {
-	var AddCoinName string
-	var AddCoinPriceEUR float64
+func GetCoinBySymbol(DB queryer, coinSymbol string) (coinNameReturn string, coinPriceEURReturn float64) {
+	coin, priceEur := findCoinBySymbol(DB, coinSymbol)
 
-	// Get all coins with this symbol
-	rows, err := DB.Query("SELECT name, priceeur FROM coins WHERE symbol = $1", coinSymbol)
-
-	if err != nil {
-		panic(err)
-	}
-
-	// Foreach coin
-	for rows.Next() {
-		var CoinName string
-		var CoinPriceEUR float64
+	return coin.Name, priceEur
+}
 
-		err = rows.Scan(&CoinName, &CoinPriceEUR)
+// findCoinBySymbol - coin with this symbol and its price, empty name if it does not exist
+// Symbols shared by several coins always resolve to the one listed on CMC first, which has the lowest CMC ID.
+func findCoinBySymbol(DB queryer, coinSymbol string) (CoinSymbolInfo, float64) {
+	var coin CoinSymbolInfo
+	var priceEur float64
 
-		if err != nil {
-			panic(err)
-		}
+	err := DB.QueryRow("SELECT coinid, name, symbol, priceeur FROM coins WHERE symbol = $1 ORDER BY cmcid LIMIT 1", coinSymbol).Scan(&coin.CoinID, &coin.Name, &coin.Symbol, &priceEur)
 
-		AddCoinName = CoinName
-		AddCoinPriceEUR = CoinPriceEUR
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
 	}
 
-	return AddCoinName, AddCoinPriceEUR
+	return coin, priceEur
 }
 
 // CheckCoinBelongsToUser -
@@ -362,11 +517,11 @@ func CheckCoinBelongsToUser(DB *sql.DB, coinid string, userID int) int {
 }
 
 // CheckUserHasCoin -
-func CheckUserHasCoin(DB *sql.DB, userID int, AddCoinName string, coinSymbol string) int {
+func CheckUserHasCoin(DB *sql.DB, userID int, portfolioID int, AddCoinName string, coinSymbol string) int {
 	// Check to see if user has this coin in their portfolio already
 	count := 0
 
-	row := DB.QueryRow("SELECT COUNT(*) FROM usercoins where userid = $1 AND name = $2 AND symbol = $3", userID, AddCoinName, coinSymbol)
+	row := DB.QueryRow("SELECT COUNT(*) FROM usercoins where userid = $1 AND portfolioid = $2 AND name = $3 AND symbol = $4", userID, portfolioID, AddCoinName, coinSymbol)
 
 	err := row.Scan(&count)
 
@@ -378,11 +533,11 @@ func CheckUserHasCoin(DB *sql.DB, userID int, AddCoinName string, coinSymbol str
 }
 
 // CreateCoin -
-func CreateCoin(DB *sql.DB, userID int, AddCoinName string, coinSymbol string, convertCoinInvested float64, convertCoinAmount float64, AddCoinPriceEUR float64, coinLives string) int {
+func CreateCoin(DB queryer, userID int, portfolioID int, AddCoinName string, coinSymbol string, convertCoinInvested float64, convertCoinAmount float64, AddCoinPriceEUR float64, coinLives string) int {
 	lastInsertID := 0
 
-	err := DB.QueryRow("INSERT INTO usercoins(userid, name, symbol, invested, amount, madelost, worth, priceeur, lives, date_added, date_updated) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning usercoinid;",
-		userID, AddCoinName, coinSymbol, convertCoinInvested, convertCoinAmount, 0, 0, AddCoinPriceEUR, coinLives, helpers.GetCurrentDateTime(), helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+	err := DB.QueryRow("INSERT INTO usercoins(userid, portfolioid, name, symbol, invested, amount, madelost, worth, priceeur, lives, date_added, date_updated) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) returning usercoinid;",
+		userID, portfolioID, AddCoinName, coinSymbol, convertCoinInvested, convertCoinAmount, 0, 0, AddCoinPriceEUR, coinLives, helpers.GetCurrentDateTime(), helpers.GetCurrentDateTime()).Scan(&lastInsertID)
 
 	if err != nil {
 		panic(err)
@@ -413,5 +568,63 @@ func RemoveCoin(DB *sql.DB, coinid string) bool {
 		return false
 	}
 
+	_, err = DB.Exec("DELETE FROM holdingtags where usercoinid = $1", coinid)
+
+	if err != nil {
+		return false
+	}
+
+	// Target alerts of the holding go with it
+	_, err = DB.Exec("DELETE FROM alertfirings where alertid IN (SELECT alertid FROM alerts WHERE usercoinid = $1)", coinid)
+
+	if err != nil {
+		return false
+	}
+
+	_, err = DB.Exec("DELETE FROM alerts where usercoinid = $1", coinid)
+
+	if err != nil {
+		return false
+	}
+
 	return true
 }
+
+// GetUserHoldings - get user coins of the portfolio as they are stored, without syncing prices
+// AllPortfolios returns coins of every portfolio.
+func GetUserHoldings(DB *sql.DB, userID int, portfolioID int) []Coin {
+	rows, err := DB.Query("SELECT usercoinid, portfolioid, name, symbol, invested, amount, madelost, worth, priceeur, lives, date_added, date_updated, take_profit, stop_loss, notes FROM usercoins WHERE userid = $1 AND ($2 = 0 OR portfolioid = $2) ORDER BY usercoinid", userID, portfolioID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var coins []Coin
+
+	// Foreach coin
+	for rows.Next() {
+		var coin Coin
+		var takeProfit, stopLoss float64
+
+		err = rows.Scan(&coin.UserCoinID, &coin.PortfolioID, &coin.Name, &coin.Symbol, &coin.Invested, &coin.Amount, &coin.MadeLost, &coin.Worth, &coin.PriceEur, &coin.Lives, &coin.DateAdded, &coin.DateUpdated,
+			&takeProfit, &stopLoss, &coin.Notes)
+
+		if err != nil {
+			panic(err)
+		}
+
+		if takeProfit > 0 {
+			coin.TakeProfit = &Target{Price: takeProfit}
+		}
+
+		if stopLoss > 0 {
+			coin.StopLoss = &Target{Price: stopLoss}
+		}
+
+		coins = append(coins, coin)
+	}
+
+	return coins
+}
@@ -0,0 +1,386 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// DigestFrequencies - how often digest can be sent, off disables it
+var DigestFrequencies = []string{"off", "daily", "weekly"}
+
+// DigestChannels - where digest can be delivered, chat channel from ChannelID is used in addition
+var DigestChannels = []string{"email", "webhook", "both", "none"}
+
+// DigestMoversLimit - number of top gainers and losers in the digest
+const DigestMoversLimit = 3
+
+// DigestSettings - user's digest preferences, stored in usersettings
+type DigestSettings struct {
+	Frequency string `json:"frequency"`
+	Time      string `json:"time"`
+	Weekday   string `json:"weekday"`
+	Timezone  string `json:"timezone"`
+	Channel   string `json:"channel"`
+	ChannelID int    `json:"channelid"`
+	LastSent  string `json:"last_sent"`
+}
+
+// DigestMover - change of a coin held by the user since the last digest
+type DigestMover struct {
+	Symbol        string  `json:"symbol"`
+	Name          string  `json:"name"`
+	PriceFrom     float64 `json:"price_from"`
+	PriceTo       float64 `json:"price_to"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+// Digest - summary of the portfolio since the last digest
+type Digest struct {
+	Frequency          string        `json:"frequency"`
+	DateFrom           string        `json:"date_from"`
+	DateTo             string        `json:"date_to"`
+	Invested           float64       `json:"invested"`
+	Worth              float64       `json:"worth"`
+	Profit             float64       `json:"profit"`
+	WorthChange        float64       `json:"worth_change"`
+	WorthChangePercent float64       `json:"worth_change_percent"`
+	ProfitChange       float64       `json:"profit_change"`
+	Gainers            []DigestMover `json:"gainers"`
+	Losers             []DigestMover `json:"losers"`
+	Alerts             []AlertFiring `json:"alerts"`
+}
+
+// GetDigestSettings - user's digest settings with defaults for the ones not set
+func GetDigestSettings(DB *sql.DB, userID int) DigestSettings {
+	digestSettings := DigestSettings{
+		Frequency: "off",
+		Time:      "08:00",
+		Weekday:   "monday",
+		Timezone:  "UTC",
+		Channel:   "email",
+	}
+
+	for _, setting := range GetUserSettings(DB, userID) {
+		switch setting.Name {
+		case "digest_frequency":
+			digestSettings.Frequency = setting.Value
+		case "digest_time":
+			digestSettings.Time = setting.Value
+		case "digest_weekday":
+			digestSettings.Weekday = setting.Value
+		case "digest_timezone":
+			digestSettings.Timezone = setting.Value
+		case "digest_channel":
+			digestSettings.Channel = setting.Value
+		case "digest_channelid":
+			digestSettings.ChannelID, _ = strconv.Atoi(setting.Value)
+		case "digest_last":
+			digestSettings.LastSent = setting.Value
+		}
+	}
+
+	return digestSettings
+}
+
+// ValidateDigestSettings - check frequency, time, weekday, timezone and channel
+func ValidateDigestSettings(digestSettings DigestSettings) error {
+	if indexOf(DigestFrequencies, digestSettings.Frequency) < 0 {
+		return errors.New("Frequency has to be one of: " + strings.Join(DigestFrequencies, ", ") + ".")
+	}
+
+	if indexOf(DigestChannels, digestSettings.Channel) < 0 {
+		return errors.New("Channel has to be one of: " + strings.Join(DigestChannels, ", ") + ".")
+	}
+
+	if _, _, ok := parseDigestTime(digestSettings.Time); !ok {
+		return errors.New("Time has to be in HH:MM format.")
+	}
+
+	if _, ok := parseWeekday(digestSettings.Weekday); !ok {
+		return errors.New("Please provide a valid weekday.")
+	}
+
+	if _, err := time.LoadLocation(digestSettings.Timezone); err != nil {
+		return errors.New("Please provide a valid timezone, e.g. Europe/Vilnius.")
+	}
+
+	return nil
+}
+
+// SetDigestSettings - store digest settings
+// When digest gets enabled, it starts from now so the first one is sent at the next scheduled time.
+func SetDigestSettings(DB *sql.DB, userID int, digestSettings DigestSettings) {
+	if digestSettings.Frequency != "off" && GetDigestSettings(DB, userID).Frequency == "off" {
+		markDigestSent(DB, userID)
+	}
+
+	SetUserSetting(DB, userID, "digest_frequency", digestSettings.Frequency)
+	SetUserSetting(DB, userID, "digest_time", digestSettings.Time)
+	SetUserSetting(DB, userID, "digest_weekday", digestSettings.Weekday)
+	SetUserSetting(DB, userID, "digest_timezone", digestSettings.Timezone)
+	SetUserSetting(DB, userID, "digest_channel", digestSettings.Channel)
+	SetUserSetting(DB, userID, "digest_channelid", strconv.Itoa(digestSettings.ChannelID))
+}
+
+// SendDigests - send digest to every opted-in user whose scheduled time has passed since the last one
+func SendDigests(minutes int, DB *sql.DB) {
+	d := time.Duration(minutes) * time.Minute
+
+	for range time.Tick(d) {
+		rows, err := DB.Query("SELECT userid FROM usersettings WHERE name = $1 AND value <> $2", "digest_frequency", "off")
+
+		if err != nil {
+			panic(err)
+		}
+
+		var userIDs []int
+
+		// Foreach user with digest enabled
+		for rows.Next() {
+			var userID int
+
+			if err = rows.Scan(&userID); err != nil {
+				panic(err)
+			}
+
+			userIDs = append(userIDs, userID)
+		}
+
+		rows.Close()
+
+		for _, userID := range userIDs {
+			digestSettings := GetDigestSettings(DB, userID)
+
+			if !digestDue(digestSettings, time.Now()) {
+				continue
+			}
+
+			SendDigest(DB, userID, BuildDigest(DB, userID, digestSettings), digestSettings)
+		}
+	}
+}
+
+// SendDigest - deliver digest through user's channels and remember when and at what totals it was sent
+func SendDigest(DB *sql.DB, userID int, digest Digest, digestSettings DigestSettings) {
+	if digestSettings.Channel == "email" || digestSettings.Channel == "both" {
+		QueueTemplateEmail(DB, userID, "portfolio.digest", "digest", digest)
+	}
+
+	if digestSettings.Channel == "webhook" || digestSettings.Channel == "both" {
+		QueueWebhookEvent(DB, userID, "portfolio.digest", digest)
+	}
+
+	if digestSettings.ChannelID > 0 {
+		QueueChannelMessage(DB, userID, digestSettings.ChannelID, "portfolio.digest", digest)
+	}
+
+	markDigestSent(DB, userID)
+}
+
+// BuildDigest - build user's digest for the period since the last one
+func BuildDigest(DB *sql.DB, userID int, digestSettings DigestSettings) Digest {
+	now := time.Now()
+
+	since, err := helpers.ParseDateTime(digestSettings.LastSent)
+
+	if digestSettings.LastSent == "" || err != nil {
+		since = now.AddDate(0, 0, -1)
+
+		if digestSettings.Frequency == "weekly" {
+			since = now.AddDate(0, 0, -7)
+		}
+	}
+
+	digest := Digest{
+		Frequency: digestSettings.Frequency,
+		DateFrom:  helpers.FormatDateTime(since),
+		DateTo:    helpers.FormatDateTime(now),
+		Gainers:   []DigestMover{},
+		Losers:    []DigestMover{},
+	}
+
+	// Stored holding values are refreshed by UpdateUsersCoins, so they are current enough for a digest
+	holdings := GetUserHoldings(DB, userID, AllPortfolios)
+
+	movers := make(map[string]*DigestMover)
+	var symbols []string
+
+	for _, holding := range holdings {
+		digest.Invested += holding.Invested
+		digest.Worth += holding.Worth
+
+		mover, ok := movers[holding.Symbol]
+
+		if !ok {
+			priceFrom, found := GetCoinPriceAt(DB, GetCoinIDBySymbol(DB, holding.Symbol), since)
+
+			if !found || priceFrom <= 0 {
+				continue
+			}
+
+			mover = &DigestMover{Symbol: holding.Symbol, Name: holding.Name, PriceFrom: priceFrom, PriceTo: holding.PriceEur}
+			movers[holding.Symbol] = mover
+			symbols = append(symbols, holding.Symbol)
+		}
+
+		mover.Change += holding.Amount * (mover.PriceTo - mover.PriceFrom)
+	}
+
+	digest.Profit = digest.Worth - digest.Invested
+
+	previousWorth, previousProfit, ok := lastDigestTotals(DB, userID)
+
+	if !ok {
+		previous := GetUserSnapshotBefore(DB, userID, AllPortfolios, since.Format(SnapshotDateFormat))
+
+		previousWorth, previousProfit, ok = previous.Worth, previous.Profit, previous.SnapshotID > 0
+	}
+
+	if ok {
+		digest.WorthChange = digest.Worth - previousWorth
+		digest.ProfitChange = digest.Profit - previousProfit
+		digest.WorthChangePercent = percentOf(digest.WorthChange, previousWorth)
+	}
+
+	var ranked []DigestMover
+
+	for _, symbol := range symbols {
+		mover := movers[symbol]
+
+		mover.ChangePercent = roundNumber((mover.PriceTo - mover.PriceFrom) / mover.PriceFrom * 100)
+		mover.Change = roundNumber(mover.Change)
+
+		ranked = append(ranked, *mover)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].ChangePercent > ranked[j].ChangePercent
+	})
+
+	for i := 0; i < len(ranked) && len(digest.Gainers) < DigestMoversLimit; i++ {
+		if ranked[i].ChangePercent > 0 {
+			digest.Gainers = append(digest.Gainers, ranked[i])
+		}
+	}
+
+	for i := len(ranked) - 1; i >= 0 && len(digest.Losers) < DigestMoversLimit; i-- {
+		if ranked[i].ChangePercent < 0 {
+			digest.Losers = append(digest.Losers, ranked[i])
+		}
+	}
+
+	digest.Alerts = GetUserAlertFiringsSince(DB, userID, digest.DateFrom)
+
+	if digest.Alerts == nil {
+		digest.Alerts = []AlertFiring{}
+	}
+
+	digest.Invested = roundNumber(digest.Invested)
+	digest.Worth = roundNumber(digest.Worth)
+	digest.Profit = roundNumber(digest.Profit)
+	digest.WorthChange = roundNumber(digest.WorthChange)
+	digest.ProfitChange = roundNumber(digest.ProfitChange)
+
+	return digest
+}
+
+// digestDue - check if the latest scheduled time in user's timezone is after the last digest
+func digestDue(digestSettings DigestSettings, now time.Time) bool {
+	location, err := time.LoadLocation(digestSettings.Timezone)
+
+	if err != nil {
+		return false
+	}
+
+	hour, minute, ok := parseDigestTime(digestSettings.Time)
+
+	if !ok {
+		return false
+	}
+
+	now = now.In(location)
+
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, location)
+
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	if digestSettings.Frequency == "weekly" {
+		weekday, _ := parseWeekday(digestSettings.Weekday)
+
+		for scheduled.Weekday() != weekday {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+	}
+
+	if digestSettings.LastSent == "" {
+		return true
+	}
+
+	lastSent, err := helpers.ParseDateTime(digestSettings.LastSent)
+
+	return err != nil || lastSent.Before(scheduled)
+}
+
+// markDigestSent - remember time and totals of the digest, next one is compared against them
+func markDigestSent(DB *sql.DB, userID int) {
+	var worth float64
+	var invested float64
+
+	for _, holding := range GetUserHoldings(DB, userID, AllPortfolios) {
+		worth += holding.Worth
+		invested += holding.Invested
+	}
+
+	SetUserSetting(DB, userID, "digest_last", helpers.GetCurrentDateTime())
+	SetUserSetting(DB, userID, "digest_last_worth", strconv.FormatFloat(worth, 'f', 2, 64))
+	SetUserSetting(DB, userID, "digest_last_profit", strconv.FormatFloat(worth-invested, 'f', 2, 64))
+}
+
+// lastDigestTotals - worth and profit at the time of the last digest
+func lastDigestTotals(DB *sql.DB, userID int) (float64, float64, bool) {
+	worth, err := strconv.ParseFloat(GetUserSetting(DB, userID, "digest_last_worth"), 64)
+
+	if err != nil {
+		return 0, 0, false
+	}
+
+	profit, err := strconv.ParseFloat(GetUserSetting(DB, userID, "digest_last_profit"), 64)
+
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return worth, profit, true
+}
+
+// parseDigestTime - hour and minute from HH:MM
+func parseDigestTime(value string) (int, int, bool) {
+	parsed, err := time.Parse("15:04", value)
+
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return parsed.Hour(), parsed.Minute(), true
+}
+
+// parseWeekday - weekday from its english name
+func parseWeekday(value string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), value) {
+			return weekday, true
+		}
+	}
+
+	return time.Sunday, false
+}
@@ -0,0 +1,268 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// EmailMaxAttempts - failed emails are retried with exponential backoff up to this many attempts
+const EmailMaxAttempts = 5
+
+// EmailNotificationsSetting - usersettings name with events the user receives by email
+const EmailNotificationsSetting = "email_notifications"
+
+// EmailNotificationEvents - events that can be sent by email
+var EmailNotificationEvents = []string{"alert.fired", "daily.summary"}
+
+// DefaultEmailNotifications - events sent by email until the user changes preferences
+var DefaultEmailNotifications = []string{"alert.fired"}
+
+// QueuedEmail - email in the outbox
+type QueuedEmail struct {
+	EmailID     int    `json:"emailid"`
+	Event       string `json:"event"`
+	ToAddress   string `json:"to_address"`
+	Subject     string `json:"subject"`
+	BodyText    string `json:"-"`
+	BodyHTML    string `json:"-"`
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error"`
+	NextAttempt string `json:"next_attempt"`
+	DateAdded   string `json:"date_added"`
+	DateSent    string `json:"date_sent"`
+}
+
+// EmailTemplateData - data available in email templates
+// Amounts in Data are in EUR, templates show them in the currency of user's default portfolio with Money.
+type EmailTemplateData struct {
+	APPName      string
+	EmailAddress string
+	Currency     string
+	Rate         float64
+	Data         interface{}
+}
+
+// Money - EUR amount converted into the currency of the email, e.g. "12.50 USD"
+func (data EmailTemplateData) Money(value float64) string {
+	return fmt.Sprintf("%.2f %s", value*data.Rate, data.Currency)
+}
+
+// SignedMoney - EUR amount converted into the currency of the email, with the sign shown for gains too
+func (data EmailTemplateData) SignedMoney(value float64) string {
+	return fmt.Sprintf("%+.2f %s", value*data.Rate, data.Currency)
+}
+
+// emailColumns - email columns in the order they are scanned by scanEmail
+const emailColumns = "emailid, event, to_address, subject, body_text, body_html, status, attempts, last_error, next_attempt, date_added, date_sent"
+
+// ValidateEmailNotifications - check events the user wants by email
+func ValidateEmailNotifications(events []string) error {
+	for _, event := range events {
+		if indexOf(EmailNotificationEvents, event) < 0 {
+			return errors.New("Event " + event + " can not be sent by email.")
+		}
+	}
+
+	return nil
+}
+
+// GetEmailNotifications - events the user receives by email
+func GetEmailNotifications(DB *sql.DB, userID int) []string {
+	value := GetUserSetting(DB, userID, EmailNotificationsSetting)
+
+	if value == "" {
+		return DefaultEmailNotifications
+	}
+
+	if value == "none" {
+		return []string{}
+	}
+
+	return strings.Split(value, ",")
+}
+
+// SetEmailNotifications - store events the user receives by email
+func SetEmailNotifications(DB *sql.DB, userID int, events []string) {
+	value := strings.Join(events, ",")
+
+	// Empty value means defaults, so opting out of everything is stored explicitly
+	if value == "" {
+		value = "none"
+	}
+
+	SetUserSetting(DB, userID, EmailNotificationsSetting, value)
+}
+
+// QueueEmailEvent - queue email for the event if the user wants it by email
+func QueueEmailEvent(DB *sql.DB, userID int, event string, data interface{}) {
+	if indexOf(GetEmailNotifications(DB, userID), event) < 0 {
+		return
+	}
+
+	QueueTemplateEmail(DB, userID, event, strings.Replace(event, ".", "_", -1), data)
+}
+
+// QueueTemplateEmail - render template for the user and store it in the outbox, it is sent by SendQueuedEmails
+func QueueTemplateEmail(DB *sql.DB, userID int, event string, templateName string, data interface{}) int {
+	return QueueTemplateEmailTo(DB, userID, GetUserProfile(DB, userID).EmailAddress, event, templateName, data)
+}
+
+// QueueTemplateEmailTo - render template for any email address and store it in the outbox of the user sending it
+// These are queued by background workers too, so templates that fail to render are logged and skipped.
+func QueueTemplateEmailTo(DB *sql.DB, userID int, emailAddress string, event string, templateName string, data interface{}) int {
+	if emailAddress == "" || !helpers.EmailTemplateExists(templateName) {
+		return 0
+	}
+
+	currency := GetUserCurrency(DB, userID)
+
+	rate, ok := GetFXRate(DB, currency)
+
+	if !ok {
+		currency = "EUR"
+	}
+
+	email, err := helpers.RenderEmail(templateName, EmailTemplateData{
+		APPName:      helpers.GetConfig().APPName,
+		EmailAddress: emailAddress,
+		Currency:     currency,
+		Rate:         rate,
+		Data:         data,
+	})
+
+	if err != nil {
+		log.Printf("Email template %s could not be rendered for user %d: %s", templateName, userID, err)
+
+		return 0
+	}
+
+	email.To = emailAddress
+
+	lastInsertID := 0
+
+	err = DB.QueryRow("INSERT INTO emails(userid, event, to_address, subject, body_text, body_html, status, next_attempt, date_added) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) returning emailid;",
+		userID, event, email.To, email.Subject, email.BodyText, email.BodyHTML, "pending", helpers.GetCurrentDateTime(), helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// GetUserEmails - latest emails sent (or to be sent) to the user
+func GetUserEmails(DB *sql.DB, userID int, limit int) []QueuedEmail {
+	rows, err := DB.Query("SELECT "+emailColumns+" FROM emails WHERE userid = $1 ORDER BY emailid DESC LIMIT $2", userID, limit)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var emails []QueuedEmail
+
+	// Foreach email
+	for rows.Next() {
+		var email QueuedEmail
+
+		if err = scanEmail(rows, &email); err != nil {
+			panic(err)
+		}
+
+		emails = append(emails, email)
+	}
+
+	return emails
+}
+
+// SendQueuedEmails - send pending emails that are due
+func SendQueuedEmails(minutes int, DB *sql.DB) {
+	d := time.Duration(minutes) * time.Minute
+
+	for range time.Tick(d) {
+		rows, err := DB.Query("SELECT emailid FROM emails WHERE status = $1 AND next_attempt <= $2 ORDER BY emailid", "pending", helpers.GetCurrentDateTime())
+
+		if err != nil {
+			panic(err)
+		}
+
+		var emailIDs []int
+
+		// Foreach email
+		for rows.Next() {
+			var emailID int
+
+			if err = rows.Scan(&emailID); err != nil {
+				panic(err)
+			}
+
+			emailIDs = append(emailIDs, emailID)
+		}
+
+		rows.Close()
+
+		for _, emailID := range emailIDs {
+			SendQueuedEmail(DB, emailID)
+		}
+	}
+}
+
+// SendQueuedEmail - send single email from the outbox and store the outcome
+// Failed emails are retried after 1, 2, 4... minutes until EmailMaxAttempts is reached.
+func SendQueuedEmail(DB *sql.DB, emailID int) QueuedEmail {
+	var email QueuedEmail
+
+	err := scanEmail(DB.QueryRow("SELECT "+emailColumns+" FROM emails WHERE emailid = $1", emailID), &email)
+
+	if err == sql.ErrNoRows {
+		return email
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	email.Attempts++
+	email.LastError = ""
+
+	err = helpers.SendEmail(helpers.Email{To: email.ToAddress, Subject: email.Subject, BodyText: email.BodyText, BodyHTML: email.BodyHTML})
+
+	if err == nil {
+		email.Status = "sent"
+		email.DateSent = helpers.GetCurrentDateTime()
+	} else {
+		email.LastError = err.Error()
+
+		if email.Attempts >= EmailMaxAttempts {
+			email.Status = "failed"
+		} else {
+			backoff := time.Duration(math.Pow(2, float64(email.Attempts-1))) * time.Minute
+			email.NextAttempt = helpers.FormatDateTime(time.Now().Add(backoff))
+		}
+	}
+
+	_, err = DB.Exec("UPDATE emails SET status = $1, attempts = $2, last_error = $3, next_attempt = $4, date_sent = $5 WHERE emailid = $6",
+		email.Status, email.Attempts, email.LastError, email.NextAttempt, email.DateSent, email.EmailID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return email
+}
+
+// scanEmail - scan emailColumns into email
+func scanEmail(row interface{ Scan(...interface{}) error }, email *QueuedEmail) error {
+	return row.Scan(&email.EmailID, &email.Event, &email.ToAddress, &email.Subject, &email.BodyText, &email.BodyHTML, &email.Status,
+		&email.Attempts, &email.LastError, &email.NextAttempt, &email.DateAdded, &email.DateSent)
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// ExportDatasets - data sets that can be exported
+var ExportDatasets = []string{"holdings", "transactions", "snapshots", "report"}
+
+// HoldingExport - holding with the transactions (lots) it was built from
+type HoldingExport struct {
+	Coin
+	Lots []Transaction `json:"lots"`
+}
+
+// ReportLine - single coin in the portfolio report
+type ReportLine struct {
+	Name          string  `json:"name"`
+	Symbol        string  `json:"symbol"`
+	Invested      float64 `json:"invested"`
+	Worth         float64 `json:"worth"`
+	MadeLost      float64 `json:"madelost"`
+	ProfitPercent float64 `json:"profit_percent"`
+	Allocation    float64 `json:"allocation"`
+}
+
+// ReportExport - portfolio report
+type ReportExport struct {
+	Coins    []ReportLine `json:"coins"`
+	SyncData SyncInfo     `json:"syncdata"`
+}
+
+// transactionExportHeaders - columns of a transaction in exports
+var transactionExportHeaders = []string{"transactionid", "portfolioid", "type", "date_transaction", "name", "symbol", "amount", "priceeur", "fee", "total", "lives", "source"}
+
+// coinExportHeaders - columns of a holding in exports, same fields as Coin
+var coinExportHeaders = []string{"coinid", "portfolioid", "name", "symbol", "invested", "amount", "madelost", "worth", "priceeur", "lives", "date_added", "date_updated",
+	"avg_buy_price", "break_even_price", "take_profit", "stop_loss"}
+
+// GetExportData - data set of the portfolio as JSON-ready value and as a table for CSV/XLSX
+func GetExportData(DB *sql.DB, userID int, portfolioID int, dataset string) (interface{}, helpers.ExportTable) {
+	switch dataset {
+	case "holdings":
+		return exportHoldings(DB, userID, portfolioID)
+	case "transactions":
+		return exportTransactions(DB, userID, portfolioID)
+	case "snapshots":
+		return exportSnapshots(DB, userID, portfolioID)
+	}
+
+	return exportReport(DB, userID, portfolioID)
+}
+
+// exportHoldings - holdings with lots, one table row per lot
+func exportHoldings(DB *sql.DB, userID int, portfolioID int) (interface{}, helpers.ExportTable) {
+	coins, _ := GetUserCoins(userID, portfolioID, DB)
+
+	lotsByCoin := map[int][]Transaction{}
+
+	for _, transaction := range GetUserTransactions(DB, userID, portfolioID) {
+		lotsByCoin[transaction.UserCoinID] = append(lotsByCoin[transaction.UserCoinID], transaction)
+	}
+
+	holdings := []HoldingExport{}
+
+	table := helpers.ExportTable{Headers: coinExportHeaders}
+
+	for _, header := range transactionExportHeaders {
+		table.Headers = append(table.Headers, "lot_"+header)
+	}
+
+	for _, coin := range coins {
+		lots := lotsByCoin[coin.UserCoinID]
+
+		if lots == nil {
+			lots = []Transaction{}
+		}
+
+		holdings = append(holdings, HoldingExport{Coin: coin, Lots: lots})
+
+		if len(lots) == 0 {
+			table.Rows = append(table.Rows, append(coinExportRow(coin), make([]string, len(transactionExportHeaders))...))
+
+			continue
+		}
+
+		for _, lot := range lots {
+			table.Rows = append(table.Rows, append(coinExportRow(coin), transactionExportRow(lot)...))
+		}
+	}
+
+	return holdings, table
+}
+
+// exportTransactions - whole ledger
+func exportTransactions(DB *sql.DB, userID int, portfolioID int) (interface{}, helpers.ExportTable) {
+	transactions := GetUserTransactions(DB, userID, portfolioID)
+
+	if transactions == nil {
+		transactions = []Transaction{}
+	}
+
+	table := helpers.ExportTable{Headers: transactionExportHeaders}
+
+	for _, transaction := range transactions {
+		table.Rows = append(table.Rows, transactionExportRow(transaction))
+	}
+
+	return transactions, table
+}
+
+// exportSnapshots - daily portfolio totals
+func exportSnapshots(DB *sql.DB, userID int, portfolioID int) (interface{}, helpers.ExportTable) {
+	snapshots := GetUserSnapshots(DB, userID, portfolioID)
+
+	if snapshots == nil {
+		snapshots = []Snapshot{}
+	}
+
+	table := helpers.ExportTable{Headers: []string{"date_snapshot", "invested", "worth", "profit"}}
+
+	for _, snapshot := range snapshots {
+		table.Rows = append(table.Rows, []string{
+			snapshot.DateSnapshot,
+			helpers.FormatNumber(snapshot.Invested),
+			helpers.FormatNumber(snapshot.Worth),
+			helpers.FormatNumber(snapshot.Profit),
+		})
+	}
+
+	return snapshots, table
+}
+
+// exportReport - profit and allocation of every coin, with totals in the last row
+func exportReport(DB *sql.DB, userID int, portfolioID int) (interface{}, helpers.ExportTable) {
+	coins, syncInfo := GetUserCoins(userID, portfolioID, DB)
+
+	report := ReportExport{Coins: []ReportLine{}, SyncData: syncInfo}
+
+	table := helpers.ExportTable{Headers: []string{"name", "symbol", "invested", "worth", "madelost", "profit_percent", "allocation"}}
+
+	for _, coin := range coins {
+		line := ReportLine{
+			Name:          coin.Name,
+			Symbol:        coin.Symbol,
+			Invested:      coin.Invested,
+			Worth:         coin.Worth,
+			MadeLost:      coin.MadeLost,
+			ProfitPercent: percentOf(coin.MadeLost, coin.Invested),
+			Allocation:    percentOf(coin.Worth, syncInfo.Worth),
+		}
+
+		report.Coins = append(report.Coins, line)
+
+		table.Rows = append(table.Rows, []string{
+			line.Name,
+			line.Symbol,
+			helpers.FormatNumber(line.Invested),
+			helpers.FormatNumber(line.Worth),
+			helpers.FormatNumber(line.MadeLost),
+			helpers.FormatNumber(line.ProfitPercent),
+			helpers.FormatNumber(line.Allocation),
+		})
+	}
+
+	table.Rows = append(table.Rows, []string{
+		"Total",
+		"",
+		helpers.FormatNumber(syncInfo.Invested),
+		helpers.FormatNumber(syncInfo.Worth),
+		helpers.FormatNumber(syncInfo.Profit),
+		helpers.FormatNumber(percentOf(syncInfo.Profit, syncInfo.Invested)),
+		"100",
+	})
+
+	return report, table
+}
+
+// coinExportRow - holding as a table row
+func coinExportRow(coin Coin) []string {
+	return []string{
+		strconv.Itoa(coin.UserCoinID),
+		strconv.Itoa(coin.PortfolioID),
+		coin.Name,
+		coin.Symbol,
+		helpers.FormatNumber(coin.Invested),
+		helpers.FormatNumber(coin.Amount),
+		helpers.FormatNumber(coin.MadeLost),
+		helpers.FormatNumber(coin.Worth),
+		helpers.FormatNumber(coin.PriceEur),
+		coin.Lives,
+		coin.DateAdded,
+		coin.DateUpdated,
+		helpers.FormatNumber(coin.AvgBuyPrice),
+		helpers.FormatNumber(coin.BreakEven),
+		targetExportValue(coin.TakeProfit),
+		targetExportValue(coin.StopLoss),
+	}
+}
+
+// targetExportValue - target price as a table cell, empty when the holding has no target
+func targetExportValue(target *Target) string {
+	if target == nil {
+		return ""
+	}
+
+	return helpers.FormatNumber(target.Price)
+}
+
+// transactionExportRow - transaction as a table row
+func transactionExportRow(transaction Transaction) []string {
+	return []string{
+		strconv.Itoa(transaction.TransactionID),
+		strconv.Itoa(transaction.PortfolioID),
+		transaction.Type,
+		transaction.DateTransaction,
+		transaction.Name,
+		transaction.Symbol,
+		helpers.FormatNumber(transaction.Amount),
+		helpers.FormatNumber(transaction.PriceEur),
+		helpers.FormatNumber(transaction.Fee),
+		helpers.FormatNumber(transaction.Total),
+		transaction.Lives,
+		transaction.Source,
+	}
+}
+
+// percentOf - value as percentage of total, rounded to 2 decimals
+func percentOf(value float64, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return roundNumber(value / total * 100)
+}
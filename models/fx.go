@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// FXCurrencies - base currencies portfolios can use, EUR plus the ECB reference rates
+var FXCurrencies = []string{
+	"EUR", "USD", "JPY", "BGN", "CZK", "DKK", "GBP", "HUF", "PLN", "RON", "SEK", "CHF", "ISK", "NOK", "TRY",
+	"AUD", "BRL", "CAD", "CNY", "HKD", "IDR", "ILS", "INR", "KRW", "MXN", "MYR", "NZD", "PHP", "SGD", "THB", "ZAR",
+}
+
+// FXRatesURL - ECB euro foreign exchange reference rates, published every working day
+const FXRatesURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// FXRatesFromECB - rates XML from ECB
+type FXRatesFromECB struct {
+	Cube struct {
+		Cube struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// FXCurrencyExists - check if currency can be used as base currency
+func FXCurrencyExists(currency string) bool {
+	return indexOf(FXCurrencies, currency) >= 0
+}
+
+// UpdateFXRates - get latest EUR rates from ECB and store them in DB
+func UpdateFXRates(hours int, DB *sql.DB) {
+	ticker := time.Tick(time.Duration(hours) * time.Hour)
+
+	// Rates are needed right away, so first update does not wait for the ticker
+	for ; true; <-ticker {
+		response, err := http.Get(FXRatesURL)
+
+		if err != nil {
+			// Rates change once a day, keep the stored ones until the next try
+			continue
+		}
+
+		body, err := ioutil.ReadAll(response.Body)
+
+		response.Body.Close()
+
+		if err != nil {
+			continue
+		}
+
+		rates := FXRatesFromECB{}
+
+		if err = xml.Unmarshal(body, &rates); err != nil {
+			continue
+		}
+
+		for _, rate := range rates.Cube.Cube.Rates {
+			SetFXRate(DB, rate.Currency, rate.Rate)
+		}
+	}
+}
+
+// SetFXRate - create or update EUR rate of the currency
+func SetFXRate(DB *sql.DB, currency string, rate float64) {
+	result, err := DB.Exec("UPDATE fxrates SET rate = $1, date_updated = $2 WHERE currency = $3", rate, helpers.GetCurrentDateTime(), currency)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return
+	}
+
+	_, err = DB.Exec("INSERT INTO fxrates(currency, rate, date_updated) VALUES($1, $2, $3)", currency, rate, helpers.GetCurrentDateTime())
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// GetFXRate - how many units of the currency one EUR buys, false if the rate is not known yet
+func GetFXRate(DB *sql.DB, currency string) (float64, bool) {
+	if currency == "" || currency == "EUR" {
+		return 1, true
+	}
+
+	var rate float64
+
+	err := DB.QueryRow("SELECT rate FROM fxrates WHERE currency = $1", currency).Scan(&rate)
+
+	if err == sql.ErrNoRows {
+		return 1, false
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return rate, rate > 0
+}
+
+// ConversionRate - rate converting EUR values into the currency, with the currency actually used
+// EUR is used while the rate of the currency is not known yet.
+func ConversionRate(DB *sql.DB, currency string) (float64, string) {
+	rate, ok := GetFXRate(DB, currency)
+
+	if !ok {
+		return 1, "EUR"
+	}
+
+	return rate, currency
+}
+
+// ConvertCoins - convert EUR values of coins and totals into the currency
+// Values stay in EUR when the rate is not known yet; returned currency is the one actually used.
+func ConvertCoins(DB *sql.DB, coins []Coin, syncInfo SyncInfo, currency string) ([]Coin, SyncInfo, string) {
+	rate, currency := ConversionRate(DB, currency)
+
+	if rate == 1 {
+		return coins, syncInfo, currency
+	}
+
+	convert := func(value float64) float64 {
+		return roundNumber(value * rate)
+	}
+
+	converted := make([]Coin, len(coins))
+
+	for i, coin := range coins {
+		coin.Invested = convert(coin.Invested)
+		coin.Worth = convert(coin.Worth)
+		coin.MadeLost = convert(coin.MadeLost)
+		coin.PriceEur = coin.PriceEur * rate
+		coin.AvgBuyPrice = coin.AvgBuyPrice * rate
+		coin.BreakEven = coin.BreakEven * rate
+
+		// Targets are shared with the unconverted coin, so they are copied before converting
+		for _, target := range []**Target{&coin.TakeProfit, &coin.StopLoss} {
+			if *target != nil {
+				convertedTarget := **target
+				convertedTarget.Price = convertedTarget.Price * rate
+
+				*target = &convertedTarget
+			}
+		}
+
+		converted[i] = coin
+	}
+
+	syncInfo.Invested = convert(syncInfo.Invested)
+	syncInfo.Worth = convert(syncInfo.Worth)
+	syncInfo.Profit = convert(syncInfo.Profit)
+
+	return converted, syncInfo, currency
+}
@@ -0,0 +1,876 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// ImportColumnMapping - CSV column names for each transaction field, used by the generic format
+type ImportColumnMapping struct {
+	Date   string `json:"date"`
+	Type   string `json:"type"`
+	Symbol string `json:"symbol"`
+	Amount string `json:"amount"`
+	Price  string `json:"price"`
+	Total  string `json:"total"`
+	Fee    string `json:"fee"`
+	Lives  string `json:"lives"`
+	ID     string `json:"id"`
+}
+
+// ImportRow - transaction parsed from a CSV row
+type ImportRow struct {
+	Row             int     `json:"row"`
+	Type            string  `json:"type"`
+	Name            string  `json:"name"`
+	Symbol          string  `json:"symbol"`
+	Amount          float64 `json:"amount"`
+	PriceEur        float64 `json:"priceeur"`
+	Fee             float64 `json:"fee"`
+	Total           float64 `json:"total"`
+	Lives           string  `json:"lives"`
+	DateTransaction string  `json:"date_transaction"`
+	Hash            string  `json:"hash"`
+	Status          string  `json:"status"`
+}
+
+// ImportRowError - reason why a CSV row was not imported
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportResult - outcome of an import (or of a dry-run preview)
+type ImportResult struct {
+	Format     string           `json:"format"`
+	DryRun     bool             `json:"dryrun"`
+	Imported   int              `json:"imported"`
+	Duplicates int              `json:"duplicates"`
+	Failed     int              `json:"failed"`
+	Rows       []ImportRow      `json:"rows"`
+	Errors     []ImportRowError `json:"errors"`
+	Unmatched  []string         `json:"unmatched"`
+}
+
+// importFormat - CSV format with the headers it needs and a parser for a single record
+type importFormat struct {
+	Lives   string
+	Headers []string
+	Parse   func(record map[string]string, mapping ImportColumnMapping) (ImportRow, error)
+}
+
+// importFormats - supported CSV export formats
+var importFormats = map[string]importFormat{
+	"binance": {
+		Lives:   "Binance",
+		Headers: []string{"Date(UTC)", "Pair", "Side", "Price", "Executed", "Amount", "Fee"},
+		Parse:   parseBinanceRecord,
+	},
+	"coinbase": {
+		Lives:   "Coinbase",
+		Headers: []string{"Timestamp", "Transaction Type", "Asset", "Quantity Transacted", "Spot Price Currency", "Spot Price at Transaction", "Subtotal", "Fees"},
+		Parse:   parseCoinbaseRecord,
+	},
+	"kraken": {
+		Lives:   "Kraken",
+		Headers: []string{"txid", "pair", "time", "type", "price", "cost", "fee", "vol"},
+		Parse:   parseKrakenRecord,
+	},
+	"bitstamp": {
+		Lives:   "Bitstamp",
+		Headers: []string{"Type", "Datetime", "Amount", "Value", "Rate", "Fee", "Sub Type"},
+		Parse:   parseBitstampRecord,
+	},
+	"delta": {
+		Lives:   "Delta",
+		Headers: []string{"Date", "Way", "Base amount", "Base currency (name)", "Quote amount", "Quote currency"},
+		Parse:   parseDeltaRecord,
+	},
+	"cointracking": {
+		Lives:   "CoinTracking",
+		Headers: []string{"Type", "Buy", "Cur.", "Sell", "Cur. (2)", "Date"},
+		Parse:   parseCoinTrackingRecord,
+	},
+	"blockfolio": {
+		Lives:   "Blockfolio",
+		Headers: []string{"Date", "Pair", "Operation", "Quantity", "Price"},
+		Parse:   parseBlockfolioRecord,
+	},
+	"generic": {
+		Lives: "Imported",
+		Parse: parseGenericRecord,
+	},
+}
+
+// ImportFormatExists - check if import format is supported
+func ImportFormatExists(format string) bool {
+	_, ok := importFormats[format]
+
+	return ok
+}
+
+// ImportTransactions - parse CSV export and import its transactions into the portfolio, oldest first
+// With dryRun rows are applied the same way inside a DB transaction that is rolled back, so the
+// preview marks them as new, duplicate or failed exactly as the import would.
+// Symbols maps symbols used in the export to symbols in the coins catalogue, so coins
+// reported as unmatched can be resolved by hand and imported again.
+func ImportTransactions(DB *sql.DB, userID int, portfolioID int, format string, data io.Reader, mapping ImportColumnMapping, symbols map[string]string, lives string, dryRun bool) ImportResult {
+	result := ImportResult{Format: format, DryRun: dryRun, Rows: []ImportRow{}, Errors: []ImportRowError{}, Unmatched: []string{}}
+
+	rows, rowErrors := ParseImportCSV(format, data, mapping, lives)
+
+	result.Errors = append(result.Errors, rowErrors...)
+
+	seenHashes := map[string]bool{}
+	unmatched := map[string]bool{}
+
+	var preview *sql.Tx
+
+	if dryRun {
+		tx, err := DB.Begin()
+
+		if err != nil {
+			panic(err)
+		}
+
+		defer tx.Rollback()
+
+		preview = tx
+	}
+
+	for _, row := range rows {
+		if symbol, ok := symbols[row.Symbol]; ok && symbol != "" {
+			row.Symbol = strings.ToUpper(symbol)
+		}
+
+		coinName, _ := GetCoinBySymbol(DB, row.Symbol)
+
+		if coinName == "" {
+			result.Errors = append(result.Errors, ImportRowError{Row: row.Row, Error: "Coin " + row.Symbol + " does not exist!"})
+
+			if !unmatched[row.Symbol] {
+				unmatched[row.Symbol] = true
+				result.Unmatched = append(result.Unmatched, row.Symbol)
+			}
+
+			continue
+		}
+
+		row.Name = coinName
+
+		if seenHashes[row.Hash] || CheckTransactionHashExists(DB, userID, row.Hash) > 0 {
+			row.Status = "duplicate"
+			result.Duplicates++
+			result.Rows = append(result.Rows, row)
+
+			continue
+		}
+
+		seenHashes[row.Hash] = true
+
+		transaction := Transaction{
+			PortfolioID:     portfolioID,
+			Type:            row.Type,
+			Name:            row.Name,
+			Symbol:          row.Symbol,
+			Amount:          row.Amount,
+			PriceEur:        row.PriceEur,
+			Fee:             row.Fee,
+			Total:           row.Total,
+			Lives:           row.Lives,
+			Source:          format,
+			Hash:            row.Hash,
+			DateTransaction: row.DateTransaction,
+		}
+
+		var transactionID int
+
+		if dryRun {
+			transactionID = applyTransaction(preview, userID, transaction)
+		} else {
+			transactionID = ApplyTransaction(DB, userID, transaction)
+		}
+
+		if transactionID < 1 {
+			result.Errors = append(result.Errors, ImportRowError{Row: row.Row, Error: "There is not enough " + row.Symbol + " in your portfolio to sell."})
+
+			continue
+		}
+
+		if dryRun {
+			row.Status = "new"
+		} else {
+			row.Status = "imported"
+			result.Imported++
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	result.Failed = len(result.Errors)
+
+	sort.Strings(result.Unmatched)
+
+	sort.Slice(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+
+	return result
+}
+
+// ParseImportCSV - parse CSV export into transaction rows, sorted from oldest to newest
+func ParseImportCSV(format string, data io.Reader, mapping ImportColumnMapping, lives string) ([]ImportRow, []ImportRowError) {
+	var rows []ImportRow
+	var rowErrors []ImportRowError
+
+	importFormat, ok := importFormats[format]
+
+	if !ok {
+		return rows, append(rowErrors, ImportRowError{Row: 0, Error: "This import format is not supported."})
+	}
+
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	headerRecord, err := reader.Read()
+
+	if err != nil {
+		return rows, append(rowErrors, ImportRowError{Row: 0, Error: "Could not read CSV header."})
+	}
+
+	headers := make([]string, len(headerRecord))
+	headerCount := map[string]int{}
+
+	for i, header := range headerRecord {
+		header = strings.TrimSpace(strings.TrimPrefix(header, "\ufeff"))
+
+		// Repeated columns (e.g. CoinTracking's "Cur.") become "Cur. (2)", "Cur. (3)"...
+		headerCount[header]++
+
+		if headerCount[header] > 1 {
+			header = header + " (" + strconv.Itoa(headerCount[header]) + ")"
+		}
+
+		headers[i] = header
+	}
+
+	requiredHeaders := importFormat.Headers
+
+	if format == "generic" {
+		requiredHeaders = mapping.requiredHeaders()
+
+		if len(requiredHeaders) == 0 {
+			return rows, append(rowErrors, ImportRowError{Row: 0, Error: "Please provide column mapping for date, type, symbol, amount and price or total."})
+		}
+	}
+
+	for _, required := range requiredHeaders {
+		if indexOf(headers, required) < 0 {
+			return rows, append(rowErrors, ImportRowError{Row: 0, Error: "Column " + required + " is missing."})
+		}
+	}
+
+	rowNumber := 1
+	fills := map[string]int{}
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		rowNumber++
+
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Error: err.Error()})
+
+			continue
+		}
+
+		values := map[string]string{}
+
+		for i, header := range headers {
+			if i < len(record) {
+				values[header] = strings.TrimSpace(record[i])
+			}
+		}
+
+		row, err := importFormat.Parse(values, mapping)
+
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Error: err.Error()})
+
+			continue
+		}
+
+		if err = row.complete(); err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: rowNumber, Error: err.Error()})
+
+			continue
+		}
+
+		row.Row = rowNumber
+
+		if row.Lives == "" {
+			row.Lives = lives
+		}
+
+		if row.Lives == "" {
+			row.Lives = importFormat.Lives
+		}
+
+		if row.Hash == "" {
+			row.Hash = fmt.Sprintf("%s|%s|%s|%s|%g|%g", format, row.DateTransaction, row.Type, row.Symbol, row.Amount, row.Total)
+
+			// Identical fills in the same second are separate trades, so repeats are told apart by how many came before them
+			fills[row.Hash]++
+
+			if fills[row.Hash] > 1 {
+				row.Hash = row.Hash + "|" + strconv.Itoa(fills[row.Hash])
+			}
+		} else {
+			row.Hash = format + "|" + row.Hash
+		}
+
+		hash := sha256.Sum256([]byte(row.Hash))
+		row.Hash = hex.EncodeToString(hash[:])
+
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].DateTransaction < rows[j].DateTransaction
+	})
+
+	return rows, rowErrors
+}
+
+// requiredHeaders - columns that have to be present for the generic format
+func (mapping ImportColumnMapping) requiredHeaders() []string {
+	if mapping.Date == "" || mapping.Type == "" || mapping.Symbol == "" || mapping.Amount == "" || (mapping.Price == "" && mapping.Total == "") {
+		return nil
+	}
+
+	headers := []string{mapping.Date, mapping.Type, mapping.Symbol, mapping.Amount}
+
+	for _, optional := range []string{mapping.Price, mapping.Total, mapping.Fee, mapping.Lives, mapping.ID} {
+		if optional != "" {
+			headers = append(headers, optional)
+		}
+	}
+
+	return headers
+}
+
+// complete - validate parsed row and fill in price or total from each other
+func (row *ImportRow) complete() error {
+	if row.Type != "buy" && row.Type != "sell" {
+		return errors.New("Only buy and sell transactions can be imported.")
+	}
+
+	if row.Symbol == "" {
+		return errors.New("Coin symbol is missing.")
+	}
+
+	if row.Amount <= 0 {
+		return errors.New("Amount has to be greater than 0.")
+	}
+
+	if row.Total <= 0 && row.PriceEur > 0 {
+		row.Total = row.Amount * row.PriceEur
+	}
+
+	if row.PriceEur <= 0 && row.Total > 0 {
+		row.PriceEur = row.Total / row.Amount
+	}
+
+	if row.Total <= 0 {
+		return errors.New("Price or total is missing.")
+	}
+
+	if row.Fee < 0 {
+		row.Fee = -row.Fee
+	}
+
+	return nil
+}
+
+// parseBinanceRecord - Binance spot trade history export
+func parseBinanceRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	pair := strings.ToUpper(record["Pair"])
+
+	if !strings.HasSuffix(pair, "EUR") {
+		return row, errors.New("Only EUR pairs can be imported, got " + pair + ".")
+	}
+
+	row.Symbol = strings.TrimSuffix(pair, "EUR")
+	row.Type = normalizeImportType(record["Side"])
+
+	date, err := parseImportDate(record["Date(UTC)"], time.UTC)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, _, err = parseImportAmountWithUnit(record["Executed"], row.Symbol); err != nil {
+		return row, err
+	}
+
+	if row.PriceEur, err = parseImportNumber(record["Price"]); err != nil {
+		return row, err
+	}
+
+	if row.Total, _, err = parseImportAmountWithUnit(record["Amount"], "EUR"); err != nil {
+		return row, err
+	}
+
+	row.Fee, err = parseImportFee(record["Fee"], row.Symbol, row.PriceEur)
+
+	return row, err
+}
+
+// parseCoinbaseRecord - Coinbase transaction history export
+func parseCoinbaseRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	if currency := strings.ToUpper(record["Spot Price Currency"]); currency != "EUR" {
+		return row, errors.New("Only EUR transactions can be imported, got " + currency + ".")
+	}
+
+	row.Symbol = strings.ToUpper(record["Asset"])
+	row.Type = normalizeImportType(record["Transaction Type"])
+
+	date, err := parseImportDate(record["Timestamp"], time.Local)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, err = parseImportNumber(record["Quantity Transacted"]); err != nil {
+		return row, err
+	}
+
+	if row.PriceEur, err = parseImportNumber(record["Spot Price at Transaction"]); err != nil {
+		return row, err
+	}
+
+	if row.Total, err = parseImportNumber(record["Subtotal"]); err != nil {
+		return row, err
+	}
+
+	row.Fee, err = parseImportNumber(record["Fees"])
+
+	return row, err
+}
+
+// parseKrakenRecord - Kraken trades export
+func parseKrakenRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	pair := strings.ToUpper(strings.Replace(record["pair"], "/", "", -1))
+
+	if !strings.HasSuffix(pair, "EUR") {
+		return row, errors.New("Only EUR pairs can be imported, got " + pair + ".")
+	}
+
+	row.Symbol = normalizeKrakenPair(pair)
+	row.Type = normalizeImportType(record["type"])
+	row.Hash = record["txid"]
+
+	date, err := parseImportDate(record["time"], time.UTC)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, err = parseImportNumber(record["vol"]); err != nil {
+		return row, err
+	}
+
+	if row.PriceEur, err = parseImportNumber(record["price"]); err != nil {
+		return row, err
+	}
+
+	if row.Total, err = parseImportNumber(record["cost"]); err != nil {
+		return row, err
+	}
+
+	row.Fee, err = parseImportNumber(record["fee"])
+
+	return row, err
+}
+
+// parseBitstampRecord - Bitstamp transactions export
+func parseBitstampRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	if record["Type"] != "Market" {
+		return row, errors.New("Only market transactions can be imported, got " + record["Type"] + ".")
+	}
+
+	row.Type = normalizeImportType(record["Sub Type"])
+
+	date, err := parseImportDate(record["Datetime"], time.UTC)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, row.Symbol, err = parseImportAmountWithUnit(record["Amount"]); err != nil {
+		return row, err
+	}
+
+	var unit string
+
+	if row.Total, unit, err = parseImportAmountWithUnit(record["Value"]); err != nil {
+		return row, err
+	}
+
+	if unit != "EUR" {
+		return row, errors.New("Only EUR transactions can be imported, got " + unit + ".")
+	}
+
+	if row.PriceEur, _, err = parseImportAmountWithUnit(record["Rate"]); err != nil {
+		return row, err
+	}
+
+	row.Fee, err = parseImportFee(record["Fee"], row.Symbol, row.PriceEur)
+
+	return row, err
+}
+
+// parseDeltaRecord - Delta portfolio tracker export
+func parseDeltaRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	if currency := strings.ToUpper(record["Quote currency"]); currency != "EUR" {
+		return row, errors.New("Only EUR transactions can be imported, got " + currency + ".")
+	}
+
+	row.Symbol = strings.ToUpper(record["Base currency (name)"])
+	row.Type = normalizeImportType(record["Way"])
+	row.Lives = record["Exchange"]
+
+	date, err := parseImportDate(record["Date"], time.Local)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, err = parseImportNumber(record["Base amount"]); err != nil {
+		return row, err
+	}
+
+	if row.Total, err = parseImportNumber(record["Quote amount"]); err != nil {
+		return row, err
+	}
+
+	if row.Fee, err = parseImportNumber(record["Fee amount"]); err != nil {
+		return row, err
+	}
+
+	if feeCurrency := strings.ToUpper(record["Fee currency (name)"]); feeCurrency == row.Symbol && row.Amount > 0 {
+		row.Fee = row.Fee * row.Total / row.Amount
+	} else if feeCurrency != "" && feeCurrency != "EUR" {
+		row.Fee = 0
+	}
+
+	return row, nil
+}
+
+// parseCoinTrackingRecord - CoinTracking trade table export
+// A trade buys one currency for another, one side of it has to be EUR.
+func parseCoinTrackingRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	if record["Type"] != "Trade" {
+		return row, errors.New("Only trades can be imported, got " + record["Type"] + ".")
+	}
+
+	buyCurrency := strings.ToUpper(record["Cur."])
+	sellCurrency := strings.ToUpper(record["Cur. (2)"])
+
+	buyAmount, err := parseImportNumber(record["Buy"])
+
+	if err != nil {
+		return row, err
+	}
+
+	sellAmount, err := parseImportNumber(record["Sell"])
+
+	if err != nil {
+		return row, err
+	}
+
+	if sellCurrency == "EUR" {
+		row.Type = "buy"
+		row.Symbol = buyCurrency
+		row.Amount = buyAmount
+		row.Total = sellAmount
+	} else if buyCurrency == "EUR" {
+		row.Type = "sell"
+		row.Symbol = sellCurrency
+		row.Amount = sellAmount
+		row.Total = buyAmount
+	} else {
+		return row, errors.New("Only trades against EUR can be imported, got " + buyCurrency + "/" + sellCurrency + ".")
+	}
+
+	row.Lives = record["Exchange"]
+
+	date, err := parseImportDate(record["Date"], time.Local)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Fee, err = parseImportNumber(record["Fee"]); err != nil {
+		return row, err
+	}
+
+	if feeCurrency := strings.ToUpper(record["Cur. (3)"]); feeCurrency == row.Symbol && row.Amount > 0 {
+		row.Fee = row.Fee * row.Total / row.Amount
+	} else if feeCurrency != "" && feeCurrency != "EUR" {
+		row.Fee = 0
+	}
+
+	return row, nil
+}
+
+// parseBlockfolioRecord - Blockfolio style export, one row per trade with "BTC/EUR" pair
+func parseBlockfolioRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+
+	pair := strings.Split(strings.ToUpper(record["Pair"]), "/")
+
+	if len(pair) != 2 || pair[1] != "EUR" {
+		return row, errors.New("Only EUR pairs can be imported, got " + record["Pair"] + ".")
+	}
+
+	row.Symbol = pair[0]
+	row.Type = normalizeImportType(record["Operation"])
+	row.Lives = record["Exchange"]
+
+	date, err := parseImportDate(record["Date"], time.Local)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, err = parseImportNumber(record["Quantity"]); err != nil {
+		return row, err
+	}
+
+	if row.PriceEur, err = parseImportNumber(record["Price"]); err != nil {
+		return row, err
+	}
+
+	row.Fee, err = parseImportNumber(record["Fee"])
+
+	return row, err
+}
+
+// parseGenericRecord - any CSV, columns are taken from the mapping
+func parseGenericRecord(record map[string]string, mapping ImportColumnMapping) (ImportRow, error) {
+	var row ImportRow
+	var err error
+
+	row.Symbol = strings.ToUpper(record[mapping.Symbol])
+	row.Type = normalizeImportType(record[mapping.Type])
+	row.Lives = record[mapping.Lives]
+	row.Hash = record[mapping.ID]
+
+	date, err := parseImportDate(record[mapping.Date], time.Local)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.DateTransaction = date
+
+	if row.Amount, err = parseImportNumber(record[mapping.Amount]); err != nil {
+		return row, err
+	}
+
+	if mapping.Price != "" {
+		if row.PriceEur, err = parseImportNumber(record[mapping.Price]); err != nil {
+			return row, err
+		}
+	}
+
+	if mapping.Total != "" {
+		if row.Total, err = parseImportNumber(record[mapping.Total]); err != nil {
+			return row, err
+		}
+	}
+
+	if mapping.Fee != "" {
+		row.Fee, err = parseImportNumber(record[mapping.Fee])
+	}
+
+	return row, err
+}
+
+// normalizeImportType - map exchange's transaction type to buy or sell
+func normalizeImportType(value string) string {
+	value = strings.ToLower(value)
+
+	if strings.Contains(value, "buy") {
+		return "buy"
+	} else if strings.Contains(value, "sell") {
+		return "sell"
+	}
+
+	return value
+}
+
+// krakenAssets - Kraken codes of assets listed before it dropped the X (crypto) and Z (fiat) prefixes
+var krakenAssets = map[string]string{
+	"XXBT": "BTC",
+	"XBT":  "BTC",
+	"XXDG": "DOGE",
+	"XDG":  "DOGE",
+	"XETC": "ETC",
+	"XETH": "ETH",
+	"XLTC": "LTC",
+	"XMLN": "MLN",
+	"XREP": "REP",
+	"XXLM": "XLM",
+	"XXMR": "XMR",
+	"XXRP": "XRP",
+	"XZEC": "ZEC",
+}
+
+// normalizeKrakenPair - symbol of the coin traded in a EUR pair like XXBTZEUR, XTZEUR or ADAEUR
+// The Z of ZEUR is only dropped after a known prefixed asset, newer assets may end with Z themselves.
+func normalizeKrakenPair(pair string) string {
+	asset := strings.TrimSuffix(pair, "EUR")
+
+	if prefixed := strings.TrimSuffix(asset, "Z"); prefixed != asset && krakenAssets[prefixed] != "" {
+		asset = prefixed
+	}
+
+	return normalizeKrakenAsset(asset)
+}
+
+// normalizeKrakenAsset - map Kraken's asset code to the symbol in the coins catalogue
+func normalizeKrakenAsset(asset string) string {
+	if symbol, ok := krakenAssets[asset]; ok {
+		return symbol
+	}
+
+	return asset
+}
+
+// parseImportDate - parse date from CSV and convert it to DB format, dates without a zone are in the location
+// Binance, Kraken and Bitstamp export dates in UTC, other exports in the time zone of the user.
+func parseImportDate(value string, location *time.Location) (string, error) {
+	date, err := helpers.ParseDateTimeIn(value, location)
+
+	if err != nil {
+		return "", err
+	}
+
+	return helpers.FormatDateTime(date), nil
+}
+
+// parseImportNumber - parse number, ignoring currency signs and thousand separators
+func parseImportNumber(value string) (float64, error) {
+	value = strings.NewReplacer("€", "", "$", "", ",", "", " ", "").Replace(value)
+
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid number", value)
+	}
+
+	return number, nil
+}
+
+// parseImportAmountWithUnit - parse values like "0.5BTC" or "100.00 EUR"
+// Units that are expected are matched first, as symbols like 1INCH start with digits. Otherwise the
+// number ends at the first character that can not be part of it.
+func parseImportAmountWithUnit(value string, units ...string) (float64, string, error) {
+	value = strings.TrimSpace(value)
+
+	for _, unit := range units {
+		if unit != "" && len(value) > len(unit) && strings.HasSuffix(strings.ToUpper(value), unit) {
+			number, err := parseImportNumber(value[:len(value)-len(unit)])
+
+			return number, unit, err
+		}
+	}
+
+	value = strings.TrimSpace(strings.TrimLeft(value, "€$"))
+
+	end := 0
+
+	for end < len(value) && strings.ContainsAny(value[end:end+1], "0123456789.,-") {
+		end++
+	}
+
+	number, err := parseImportNumber(value[:end])
+
+	return number, strings.ToUpper(strings.TrimSpace(value[end:])), err
+}
+
+// parseImportFee - fee in EUR, fees paid in the traded coin are converted with the trade price
+// Fees paid in any other coin (e.g. BNB) have no EUR price in the export and are left out.
+func parseImportFee(value string, symbol string, priceEur float64) (float64, error) {
+	fee, unit, err := parseImportAmountWithUnit(value, symbol, "EUR")
+
+	if err != nil || unit == "" || unit == "EUR" {
+		return fee, err
+	}
+
+	if unit == symbol {
+		return fee * priceEur, nil
+	}
+
+	return 0, nil
+}
+
+// indexOf - position of value in the slice, -1 if it is not there
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+
+	return -1
+}
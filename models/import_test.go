@@ -0,0 +1,237 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+)
+
+// importDate - date as parseImportDate stores it
+func importDate(year int, month time.Month, date int, hour int, minute int, second int, location *time.Location) string {
+	return helpers.FormatDateTime(time.Date(year, month, date, hour, minute, second, 0, location))
+}
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		mapping ImportColumnMapping
+		lives   string
+		csv     string
+		rows    []ImportRow
+		errors  []ImportRowError
+	}{
+		{
+			name:   "binance sorted from oldest with fees in the coin and in other coins",
+			format: "binance",
+			csv: "Date(UTC),Pair,Side,Price,Executed,Amount,Fee\n" +
+				"2021-01-03 10:00:00,ADAEUR,SELL,0.25,1000ADA,250EUR,0.01BNB\n" +
+				"2021-01-02 03:04:05,BTCEUR,BUY,20000,0.5BTC,10000EUR,0.001BTC\n" +
+				"2021-01-04 10:00:00,BTCUSDT,BUY,30000,0.1BTC,3000USDT,3USDT\n",
+			rows: []ImportRow{
+				{Row: 3, Type: "buy", Symbol: "BTC", Amount: 0.5, PriceEur: 20000, Fee: 20, Total: 10000, Lives: "Binance", DateTransaction: importDate(2021, 1, 2, 3, 4, 5, time.UTC)},
+				{Row: 2, Type: "sell", Symbol: "ADA", Amount: 1000, PriceEur: 0.25, Total: 250, Lives: "Binance", DateTransaction: importDate(2021, 1, 3, 10, 0, 0, time.UTC)},
+			},
+			errors: []ImportRowError{{Row: 4, Error: "Only EUR pairs can be imported, got BTCUSDT."}},
+		},
+		{
+			name:   "coinbase",
+			format: "coinbase",
+			csv: "Timestamp,Transaction Type,Asset,Quantity Transacted,Spot Price Currency,Spot Price at Transaction,Subtotal,Fees\n" +
+				"2021-01-02T03:04:05Z,Buy,ETH,2,EUR,1000,2000,10\n" +
+				"2021-01-03T03:04:05Z,Send,ETH,1,EUR,1000,1000,0\n" +
+				"2021-01-04T03:04:05Z,Buy,ETH,1,USD,1200,1200,5\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "buy", Symbol: "ETH", Amount: 2, PriceEur: 1000, Fee: 10, Total: 2000, Lives: "Coinbase", DateTransaction: importDate(2021, 1, 2, 3, 4, 5, time.UTC)},
+			},
+			errors: []ImportRowError{
+				{Row: 3, Error: "Only buy and sell transactions can be imported."},
+				{Row: 4, Error: "Only EUR transactions can be imported, got USD."},
+			},
+		},
+		{
+			name:   "kraken with prefixed and new assets",
+			format: "kraken",
+			csv: "txid,pair,time,type,price,cost,fee,vol\n" +
+				"T1,XXBTZEUR,2021-01-02 03:04:05.1234,buy,20000,10000,16,0.5\n" +
+				"T2,XTZEUR,2021-01-03 03:04:05.1234,sell,2.5,250,0.4,100\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "buy", Symbol: "BTC", Amount: 0.5, PriceEur: 20000, Fee: 16, Total: 10000, Lives: "Kraken", DateTransaction: importDate(2021, 1, 2, 3, 4, 5, time.UTC)},
+				{Row: 3, Type: "sell", Symbol: "XTZ", Amount: 100, PriceEur: 2.5, Fee: 0.4, Total: 250, Lives: "Kraken", DateTransaction: importDate(2021, 1, 3, 3, 4, 5, time.UTC)},
+			},
+		},
+		{
+			name:   "bitstamp",
+			format: "bitstamp",
+			csv: "Type,Datetime,Amount,Value,Rate,Fee,Sub Type\n" +
+				"Market,\"Jan. 02, 2021, 03:04 PM\",0.50000000 BTC,10000.00 EUR,20000.00 EUR,25.00 EUR,Buy\n" +
+				"Deposit,\"Jan. 03, 2021, 03:04 PM\",1.00000000 BTC,,,,\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "buy", Symbol: "BTC", Amount: 0.5, PriceEur: 20000, Fee: 25, Total: 10000, Lives: "Bitstamp", DateTransaction: importDate(2021, 1, 2, 15, 4, 0, time.UTC)},
+			},
+			errors: []ImportRowError{{Row: 3, Error: "Only market transactions can be imported, got Deposit."}},
+		},
+		{
+			name:   "delta with fee in the coin",
+			format: "delta",
+			csv: "Date,Way,Base amount,Base currency (name),Quote amount,Quote currency,Exchange,Fee amount,Fee currency (name)\n" +
+				"2021-01-02 03:04:05,BUY,2,ETH,2000,EUR,Kraken,0.01,ETH\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "buy", Symbol: "ETH", Amount: 2, PriceEur: 1000, Fee: 10, Total: 2000, Lives: "Kraken", DateTransaction: importDate(2021, 1, 2, 3, 4, 5, time.Local)},
+			},
+		},
+		{
+			name:   "cointracking buys and sells against EUR",
+			format: "cointracking",
+			csv: "Type,Buy,Cur.,Sell,Cur.,Fee,Cur.,Exchange,Date\n" +
+				"Trade,0.5,BTC,10000,EUR,5,EUR,Bitstamp,02.01.2021 03:04\n" +
+				"Trade,1000,EUR,1,ETH,0.001,ETH,Kraken,03.01.2021 03:04\n" +
+				"Trade,1,ETH,0.05,BTC,0,BTC,Kraken,04.01.2021 03:04\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "buy", Symbol: "BTC", Amount: 0.5, PriceEur: 20000, Fee: 5, Total: 10000, Lives: "Bitstamp", DateTransaction: importDate(2021, 1, 2, 3, 4, 0, time.Local)},
+				{Row: 3, Type: "sell", Symbol: "ETH", Amount: 1, PriceEur: 1000, Fee: 1, Total: 1000, Lives: "Kraken", DateTransaction: importDate(2021, 1, 3, 3, 4, 0, time.Local)},
+			},
+			errors: []ImportRowError{{Row: 4, Error: "Only trades against EUR can be imported, got ETH/BTC."}},
+		},
+		{
+			name:   "blockfolio",
+			format: "blockfolio",
+			csv: "Date,Pair,Operation,Quantity,Price,Fee,Exchange\n" +
+				"2021-01-02 03:04:05,ETH/EUR,Sell,1,1000,2,Binance\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "sell", Symbol: "ETH", Amount: 1, PriceEur: 1000, Fee: 2, Total: 1000, Lives: "Binance", DateTransaction: importDate(2021, 1, 2, 3, 4, 5, time.Local)},
+			},
+		},
+		{
+			name:    "generic with mapped columns",
+			format:  "generic",
+			mapping: ImportColumnMapping{Date: "When", Type: "Side", Symbol: "Coin", Amount: "Qty", Total: "Cost", ID: "Ref"},
+			lives:   "Ledger",
+			csv: "When,Side,Coin,Qty,Cost,Ref\n" +
+				"2021-01-02 03:04:05,buy,btc,0.5,\"10,000\",R1\n" +
+				"2021-01-03 03:04:05,buy,btc,abc,100,R2\n",
+			rows: []ImportRow{
+				{Row: 2, Type: "buy", Symbol: "BTC", Amount: 0.5, PriceEur: 20000, Total: 10000, Lives: "Ledger", DateTransaction: importDate(2021, 1, 2, 3, 4, 5, time.Local)},
+			},
+			errors: []ImportRowError{{Row: 3, Error: "\"abc\" is not a valid number"}},
+		},
+		{
+			name:   "generic without mapping",
+			format: "generic",
+			csv:    "When,Side,Coin,Qty,Cost\n",
+			errors: []ImportRowError{{Row: 0, Error: "Please provide column mapping for date, type, symbol, amount and price or total."}},
+		},
+		{
+			name:   "missing column",
+			format: "binance",
+			csv:    "Date(UTC),Pair,Side,Price,Executed,Amount\n",
+			errors: []ImportRowError{{Row: 0, Error: "Column Fee is missing."}},
+		},
+		{
+			name:   "unknown format",
+			format: "mtgox",
+			csv:    "Date\n",
+			errors: []ImportRowError{{Row: 0, Error: "This import format is not supported."}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, rowErrors := ParseImportCSV(test.format, strings.NewReader(test.csv), test.mapping, test.lives)
+
+			// Hashes are checked on their own
+			for i := range rows {
+				if len(rows[i].Hash) != 64 {
+					t.Errorf("ParseImportCSV() row %d hash = %q, want a SHA-256 hex digest", rows[i].Row, rows[i].Hash)
+				}
+
+				rows[i].Hash = ""
+			}
+
+			if len(rows) != len(test.rows) || (len(rows) > 0 && !reflect.DeepEqual(rows, test.rows)) {
+				t.Errorf("ParseImportCSV() rows = %+v, want %+v", rows, test.rows)
+			}
+
+			if len(rowErrors) != len(test.errors) || (len(rowErrors) > 0 && !reflect.DeepEqual(rowErrors, test.errors)) {
+				t.Errorf("ParseImportCSV() errors = %+v, want %+v", rowErrors, test.errors)
+			}
+		})
+	}
+}
+
+func TestParseImportCSVHashes(t *testing.T) {
+	csv := "Date(UTC),Pair,Side,Price,Executed,Amount,Fee\n" +
+		"2021-01-02 03:04:05,BTCEUR,BUY,20000,0.5BTC,10000EUR,0\n" +
+		"2021-01-02 03:04:05,BTCEUR,BUY,20000,0.5BTC,10000EUR,0\n"
+
+	rows, _ := ParseImportCSV("binance", strings.NewReader(csv), ImportColumnMapping{}, "")
+
+	if len(rows) != 2 {
+		t.Fatalf("ParseImportCSV() gave %d rows, want 2", len(rows))
+	}
+
+	if rows[0].Hash == rows[1].Hash {
+		t.Errorf("ParseImportCSV() gave identical fills the same hash %q", rows[0].Hash)
+	}
+
+	again, _ := ParseImportCSV("binance", strings.NewReader(csv), ImportColumnMapping{}, "")
+
+	if again[0].Hash != rows[0].Hash || again[1].Hash != rows[1].Hash {
+		t.Errorf("ParseImportCSV() hashes changed between imports of the same file")
+	}
+}
+
+func TestParseImportAmountWithUnit(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		units  []string
+		amount float64
+		unit   string
+	}{
+		{"unit after the number", "0.5BTC", nil, 0.5, "BTC"},
+		{"unit after a space", "100.00 EUR", nil, 100, "EUR"},
+		{"symbol starting with digits", "20.51INCH", []string{"1INCH"}, 20.5, "1INCH"},
+		{"currency sign", "€1,250.50", nil, 1250.5, ""},
+		{"empty", "", nil, 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			amount, unit, err := parseImportAmountWithUnit(test.value, test.units...)
+
+			if err != nil {
+				t.Fatalf("parseImportAmountWithUnit() error = %v", err)
+			}
+
+			if amount != test.amount || unit != test.unit {
+				t.Errorf("parseImportAmountWithUnit() = %v %q, want %v %q", amount, unit, test.amount, test.unit)
+			}
+		})
+	}
+}
+
+func TestNormalizeKrakenPair(t *testing.T) {
+	tests := []struct {
+		pair string
+		want string
+	}{
+		{"XXBTZEUR", "BTC"},
+		{"XETHZEUR", "ETH"},
+		{"XBTEUR", "BTC"},
+		{"XTZEUR", "XTZ"},
+		{"ADAEUR", "ADA"},
+		{"XXDGEUR", "DOGE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pair, func(t *testing.T) {
+			if got := normalizeKrakenPair(test.pair); got != test.want {
+				t.Errorf("normalizeKrakenPair(%q) = %q, want %q", test.pair, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// PortfolioRoles - roles a portfolio can be shared with, from least to most permissions
+// Viewers can see the portfolio, editors can also change holdings and transactions, owners can also manage the portfolio and its members.
+var PortfolioRoles = []string{"viewer", "editor", "owner"}
+
+// PortfolioMember - user the portfolio is shared with, the portfolio owner is listed as a member too
+type PortfolioMember struct {
+	UserID       int    `json:"userid"`
+	EmailAddress string `json:"email_address"`
+	Role         string `json:"role"`
+	DateAdded    string `json:"date_added"`
+}
+
+// PortfolioInvitation - invitation to join a portfolio, sent to an email address
+type PortfolioInvitation struct {
+	InvitationID  int    `json:"invitationid"`
+	PortfolioID   int    `json:"portfolioid"`
+	PortfolioName string `json:"portfolio_name"`
+	InvitedBy     string `json:"invited_by"`
+	EmailAddress  string `json:"email_address"`
+	Role          string `json:"role"`
+	Status        string `json:"status"`
+	DateAdded     string `json:"date_added"`
+}
+
+// invitationColumns - invitation columns in the order they are scanned by scanInvitation
+const invitationColumns = "i.invitationid, i.portfolioid, p.name, u.email_address, i.email_address, i.role, i.status, i.date_added"
+
+// invitationTables - invitations joined with their portfolio and the user who sent them
+const invitationTables = "portfolioinvitations i JOIN portfolios p ON p.portfolioid = i.portfolioid JOIN users u ON u.userid = i.userid"
+
+// ValidatePortfolioRole - check role the portfolio is shared with
+func ValidatePortfolioRole(role string) error {
+	if indexOf(PortfolioRoles, role) < 0 {
+		return errors.New("Role has to be one of: " + strings.Join(PortfolioRoles, ", ") + ".")
+	}
+
+	return nil
+}
+
+// PortfolioRoleAllows - check if role has at least the permissions of the required role
+func PortfolioRoleAllows(role string, required string) bool {
+	return indexOf(PortfolioRoles, role) >= indexOf(PortfolioRoles, required) && indexOf(PortfolioRoles, role) >= 0
+}
+
+// GetMemberPortfolio - portfolio the user owns or is a member of, with the user's role; PortfolioID is 0 if the user has no access
+func GetMemberPortfolio(DB *sql.DB, userID int, portfolioID int) Portfolio {
+	portfolio := GetUserPortfolio(DB, userID, portfolioID)
+
+	if portfolio.PortfolioID > 0 {
+		return portfolio
+	}
+
+	err := DB.QueryRow("SELECT "+prefixColumns("p.", portfolioColumns)+", m.role FROM portfolios p JOIN portfoliomembers m ON m.portfolioid = p.portfolioid WHERE p.portfolioid = $1 AND m.userid = $2", portfolioID, userID).Scan(
+		&portfolio.PortfolioID, &portfolio.OwnerID, &portfolio.Name, &portfolio.Currency, &portfolio.Endpoint, &portfolio.EndpointEnabled, &portfolio.DateAdded, &portfolio.Role)
+
+	if err == sql.ErrNoRows {
+		return Portfolio{}
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return portfolio
+}
+
+// GetSharedPortfolios - portfolios other users have shared with the user
+func GetSharedPortfolios(DB *sql.DB, userID int) []Portfolio {
+	rows, err := DB.Query("SELECT "+prefixColumns("p.", portfolioColumns)+", m.role FROM portfolios p JOIN portfoliomembers m ON m.portfolioid = p.portfolioid WHERE m.userid = $1 ORDER BY p.portfolioid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var portfolios []Portfolio
+
+	// Foreach portfolio
+	for rows.Next() {
+		var portfolio Portfolio
+
+		err = rows.Scan(&portfolio.PortfolioID, &portfolio.OwnerID, &portfolio.Name, &portfolio.Currency, &portfolio.Endpoint, &portfolio.EndpointEnabled, &portfolio.DateAdded, &portfolio.Role)
+
+		if err != nil {
+			panic(err)
+		}
+
+		portfolios = append(portfolios, portfolio)
+	}
+
+	return portfolios
+}
+
+// GetPortfolioMembers - owner of the portfolio followed by users it is shared with
+func GetPortfolioMembers(DB *sql.DB, portfolio Portfolio) []PortfolioMember {
+	members := []PortfolioMember{{
+		UserID:       portfolio.OwnerID,
+		EmailAddress: GetUserProfile(DB, portfolio.OwnerID).EmailAddress,
+		Role:         "owner",
+		DateAdded:    portfolio.DateAdded,
+	}}
+
+	rows, err := DB.Query("SELECT m.userid, u.email_address, m.role, m.date_added FROM portfoliomembers m JOIN users u ON u.userid = m.userid WHERE m.portfolioid = $1 ORDER BY m.memberid", portfolio.PortfolioID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	// Foreach member
+	for rows.Next() {
+		var member PortfolioMember
+
+		if err = rows.Scan(&member.UserID, &member.EmailAddress, &member.Role, &member.DateAdded); err != nil {
+			panic(err)
+		}
+
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// SetPortfolioMember - add user to the portfolio or change the role the user has
+func SetPortfolioMember(DB *sql.DB, portfolioID int, userID int, role string) {
+	result, err := DB.Exec("UPDATE portfoliomembers SET role = $1 WHERE portfolioid = $2 AND userid = $3", role, portfolioID, userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return
+	}
+
+	_, err = DB.Exec("INSERT INTO portfoliomembers(portfolioid, userid, role, date_added) VALUES($1, $2, $3, $4)", portfolioID, userID, role, helpers.GetCurrentDateTime())
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// IsPortfolioMember - check if user is a member of the portfolio, not counting the owner
+func IsPortfolioMember(DB *sql.DB, portfolioID int, userID int) bool {
+	count := 0
+
+	err := DB.QueryRow("SELECT COUNT(*) FROM portfoliomembers WHERE portfolioid = $1 AND userid = $2", portfolioID, userID).Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count > 0
+}
+
+// RemovePortfolioMember - stop sharing the portfolio with the user
+func RemovePortfolioMember(DB *sql.DB, portfolioID int, userID int) bool {
+	result, err := DB.Exec("DELETE FROM portfoliomembers WHERE portfolioid = $1 AND userid = $2", portfolioID, userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed > 0
+}
+
+// NormalizeEmailAddress - email address as invitations are stored and matched
+func NormalizeEmailAddress(emailAddress string) string {
+	return strings.ToLower(strings.TrimSpace(emailAddress))
+}
+
+// HasPortfolioAccess - check if user with the email address already owns or is a member of the portfolio
+func HasPortfolioAccess(DB *sql.DB, portfolio Portfolio, emailAddress string) bool {
+	for _, member := range GetPortfolioMembers(DB, portfolio) {
+		if NormalizeEmailAddress(member.EmailAddress) == NormalizeEmailAddress(emailAddress) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CreatePortfolioInvitation - invite email address to the portfolio and queue the invitation email
+// Pending invitation for the same address is replaced, so the role can be changed by inviting again.
+func CreatePortfolioInvitation(DB *sql.DB, userID int, portfolio Portfolio, emailAddress string, role string) int {
+	emailAddress = NormalizeEmailAddress(emailAddress)
+
+	_, err := DB.Exec("DELETE FROM portfolioinvitations WHERE portfolioid = $1 AND email_address = $2 AND status = $3", portfolio.PortfolioID, emailAddress, "pending")
+
+	if err != nil {
+		panic(err)
+	}
+
+	lastInsertID := 0
+
+	err = DB.QueryRow("INSERT INTO portfolioinvitations(portfolioid, userid, email_address, role, status, date_added) VALUES($1, $2, $3, $4, $5, $6) returning invitationid;",
+		portfolio.PortfolioID, userID, emailAddress, role, "pending", helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	QueueTemplateEmailTo(DB, userID, emailAddress, "portfolio.invitation", "portfolio_invitation", GetPortfolioInvitation(DB, lastInsertID))
+
+	return lastInsertID
+}
+
+// GetPortfolioInvitation - get invitation by ID, InvitationID is 0 if it does not exist
+func GetPortfolioInvitation(DB *sql.DB, invitationID int) PortfolioInvitation {
+	var invitation PortfolioInvitation
+
+	err := scanInvitation(DB.QueryRow("SELECT "+invitationColumns+" FROM "+invitationTables+" WHERE i.invitationid = $1", invitationID), &invitation)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return invitation
+}
+
+// GetPortfolioInvitations - pending invitations to the portfolio
+func GetPortfolioInvitations(DB *sql.DB, portfolioID int) []PortfolioInvitation {
+	return queryInvitations(DB, "SELECT "+invitationColumns+" FROM "+invitationTables+" WHERE i.portfolioid = $1 AND i.status = $2 ORDER BY i.invitationid", portfolioID, "pending")
+}
+
+// GetUserInvitations - pending invitations sent to user's email address
+func GetUserInvitations(DB *sql.DB, userID int) []PortfolioInvitation {
+	emailAddress := NormalizeEmailAddress(GetUserProfile(DB, userID).EmailAddress)
+
+	return queryInvitations(DB, "SELECT "+invitationColumns+" FROM "+invitationTables+" WHERE i.email_address = $1 AND i.status = $2 ORDER BY i.invitationid", emailAddress, "pending")
+}
+
+// RespondToInvitation - accept or decline pending invitation sent to user's email address
+func RespondToInvitation(DB *sql.DB, userID int, invitationID int, accept bool) bool {
+	invitation := GetPortfolioInvitation(DB, invitationID)
+
+	if invitation.InvitationID < 1 || invitation.Status != "pending" || invitation.EmailAddress != NormalizeEmailAddress(GetUserProfile(DB, userID).EmailAddress) {
+		return false
+	}
+
+	status := "declined"
+
+	if accept {
+		status = "accepted"
+
+		// Owner accepting invitation to own portfolio would end up with two roles, so it is only marked as accepted
+		if GetUserPortfolio(DB, userID, invitation.PortfolioID).PortfolioID < 1 {
+			SetPortfolioMember(DB, invitation.PortfolioID, userID, invitation.Role)
+		}
+	}
+
+	_, err := DB.Exec("UPDATE portfolioinvitations SET status = $1 WHERE invitationid = $2", status, invitationID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return true
+}
+
+// RemovePortfolioInvitation - cancel pending invitation to the portfolio
+func RemovePortfolioInvitation(DB *sql.DB, portfolioID int, invitationID int) bool {
+	result, err := DB.Exec("DELETE FROM portfolioinvitations WHERE invitationid = $1 AND portfolioid = $2 AND status = $3", invitationID, portfolioID, "pending")
+
+	if err != nil {
+		panic(err)
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed > 0
+}
+
+// queryInvitations - run invitations query
+func queryInvitations(DB *sql.DB, query string, args ...interface{}) []PortfolioInvitation {
+	rows, err := DB.Query(query, args...)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	invitations := []PortfolioInvitation{}
+
+	// Foreach invitation
+	for rows.Next() {
+		var invitation PortfolioInvitation
+
+		if err = scanInvitation(rows, &invitation); err != nil {
+			panic(err)
+		}
+
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations
+}
+
+// scanInvitation - scan invitationColumns into invitation
+func scanInvitation(row interface{ Scan(...interface{}) error }, invitation *PortfolioInvitation) error {
+	return row.Scan(&invitation.InvitationID, &invitation.PortfolioID, &invitation.PortfolioName, &invitation.InvitedBy,
+		&invitation.EmailAddress, &invitation.Role, &invitation.Status, &invitation.DateAdded)
+}
+
+// prefixColumns - prefix every column in comma separated list with table alias
+func prefixColumns(prefix string, columns string) string {
+	return prefix + strings.Join(strings.Split(columns, ", "), ", "+prefix)
+}
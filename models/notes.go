@@ -0,0 +1,47 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	_ "github.com/lib/pq"
+)
+
+// NotesMaxLength - longest notes on a holding or transaction, notes are markdown stored as written
+const NotesMaxLength = 10000
+
+// ValidateNotes - check notes length
+func ValidateNotes(notes string) error {
+	if len(notes) > NotesMaxLength {
+		return errors.New("Notes can be at most " + strconv.Itoa(NotesMaxLength) + " characters long.")
+	}
+
+	return nil
+}
+
+// SetHoldingNotes - replace notes of the holding
+func SetHoldingNotes(DB *sql.DB, coinid string, notes string) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE usercoins SET notes = $1 WHERE usercoinid = $2 returning usercoinid;", notes, coinid).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// SetTransactionNotes - replace notes of the transaction
+func SetTransactionNotes(DB *sql.DB, transactionID int, notes string) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE transactions SET notes = $1 WHERE transactionid = $2 returning transactionid;", notes, transactionID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// NotifyUser - send event to every notification channel of the user: webhooks and email
+func NotifyUser(DB *sql.DB, userID int, event string, data interface{}) {
+	QueueWebhookEvent(DB, userID, event, data)
+
+	QueueEmailEvent(DB, userID, event, data)
+}
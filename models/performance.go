@@ -0,0 +1,474 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// PerformancePeriods - periods performance can be calculated for
+var PerformancePeriods = []string{"1M", "3M", "YTD", "1Y", "all"}
+
+// PerformanceReturns - returns of the portfolio or a holding over a period, in percent
+// Money-weighted return (XIRR) is always annualized; annualized return is only given for periods of at least a year.
+type PerformanceReturns struct {
+	Period              string   `json:"period"`
+	From                string   `json:"from"`
+	To                  string   `json:"to"`
+	StartValue          float64  `json:"start_value"`
+	EndValue            float64  `json:"end_value"`
+	NetFlows            float64  `json:"net_flows"`
+	TimeWeightedReturn  float64  `json:"time_weighted_return"`
+	MoneyWeightedReturn *float64 `json:"money_weighted_return"`
+	AnnualizedReturn    *float64 `json:"annualized_return"`
+}
+
+// CoinPerformance - returns of a single holding
+type CoinPerformance struct {
+	CoinID      int                `json:"coinid"`
+	PortfolioID int                `json:"portfolioid"`
+	Name        string             `json:"name"`
+	Symbol      string             `json:"symbol"`
+	Returns     PerformanceReturns `json:"returns"`
+}
+
+// Performance - returns of the whole portfolio and of every holding
+type Performance struct {
+	Portfolio PerformanceReturns `json:"portfolio"`
+	Coins     []CoinPerformance  `json:"coins"`
+}
+
+// valuePoint - value at the end of a day and money put in (positive) or taken out (negative) during that day
+type valuePoint struct {
+	Date  time.Time
+	Value float64
+	Flow  float64
+}
+
+// cashFlow - money paid (negative) or received (positive) by the investor
+type cashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// ValidatePerformancePeriod - check period performance is calculated for
+func ValidatePerformancePeriod(period string) error {
+	if indexOf(PerformancePeriods, period) < 0 {
+		return errors.New("Period has to be one of: " + strings.Join(PerformancePeriods, ", ") + ".")
+	}
+
+	return nil
+}
+
+// PerformancePeriodStart - first day of the period, zero time for all history
+func PerformancePeriodStart(period string, now time.Time) time.Time {
+	today := dayOf(now)
+
+	switch period {
+	case "1M":
+		return today.AddDate(0, -1, 0)
+	case "3M":
+		return today.AddDate(0, -3, 0)
+	case "YTD":
+		return time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	case "1Y":
+		return today.AddDate(-1, 0, 0)
+	}
+
+	return time.Time{}
+}
+
+// GetPerformance - time-weighted, money-weighted and annualized returns of the portfolio and its holdings over the period
+// Portfolio values come from daily snapshots, holding values are rebuilt from the ledger and price history.
+func GetPerformance(DB *sql.DB, userID int, portfolioID int, period string) Performance {
+	now := time.Now()
+	from := PerformancePeriodStart(period, now)
+
+	coins, _ := GetUserCoins(userID, portfolioID, DB)
+	transactions := GetUserTransactions(DB, userID, portfolioID)
+
+	performance := Performance{
+		Portfolio: periodReturns(portfolioValuePoints(GetUserSnapshots(DB, userID, portfolioID), transactions), period, from),
+		Coins:     []CoinPerformance{},
+	}
+
+	for _, coin := range coins {
+		performance.Coins = append(performance.Coins, CoinPerformance{
+			CoinID:      coin.UserCoinID,
+			PortfolioID: coin.PortfolioID,
+			Name:        coin.Name,
+			Symbol:      coin.Symbol,
+			Returns:     periodReturns(coinValuePoints(DB, coin, transactions, now), period, from),
+		})
+	}
+
+	return performance
+}
+
+// ConvertPerformance - convert EUR values of the portfolio and its holdings into the currency
+// Returns are left as they are, one rate moves every value by the same factor.
+func ConvertPerformance(DB *sql.DB, performance Performance, currency string) (Performance, string) {
+	rate, currency := ConversionRate(DB, currency)
+
+	if rate == 1 {
+		return performance, currency
+	}
+
+	performance.Portfolio = convertReturns(performance.Portfolio, rate)
+
+	coins := make([]CoinPerformance, len(performance.Coins))
+
+	for i, coin := range performance.Coins {
+		coin.Returns = convertReturns(coin.Returns, rate)
+
+		coins[i] = coin
+	}
+
+	performance.Coins = coins
+
+	return performance, currency
+}
+
+// convertReturns - convert values and flows of the period with the rate
+func convertReturns(returns PerformanceReturns, rate float64) PerformanceReturns {
+	returns.StartValue = roundNumber(returns.StartValue * rate)
+	returns.EndValue = roundNumber(returns.EndValue * rate)
+	returns.NetFlows = roundNumber(returns.NetFlows * rate)
+
+	return returns
+}
+
+// portfolioValuePoints - daily portfolio values from snapshots
+// Money flows come from the ledger on days that have transactions, otherwise from the change of invested amount.
+func portfolioValuePoints(snapshots []Snapshot, transactions []Transaction) []valuePoint {
+	ledgerFlows := map[string]float64{}
+
+	for _, transaction := range transactions {
+		if date, err := helpers.ParseDateTime(transaction.DateTransaction); err == nil {
+			ledgerFlows[date.Format(SnapshotDateFormat)] += transactionFlow(transaction)
+		}
+	}
+
+	points := []valuePoint{}
+
+	for i, snapshot := range snapshots {
+		date, err := time.Parse(SnapshotDateFormat, snapshot.DateSnapshot)
+
+		if err != nil {
+			continue
+		}
+
+		point := valuePoint{Date: date, Value: snapshot.Worth}
+
+		// First snapshot is the starting value, money put in before it is not known
+		if i > 0 {
+			if flow, ok := ledgerFlows[snapshot.DateSnapshot]; ok {
+				point.Flow = flow
+			} else {
+				point.Flow = snapshot.Invested - snapshots[i-1].Invested
+			}
+		}
+
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// coinValuePoints - daily values of the holding, from the day before it was bought, or from the first day with
+// price history when that is later, until today
+// Amount held before the first transaction (coins added by hand) counts as bought on the day the holding was added.
+func coinValuePoints(DB *sql.DB, coin Coin, transactions []Transaction, now time.Time) []valuePoint {
+	amounts := map[string]float64{}
+	flows := map[string]float64{}
+
+	ledgerAmount := 0.0
+	ledgerInvested := 0.0
+
+	start := dayOf(now)
+
+	for _, transaction := range transactions {
+		if transaction.UserCoinID != coin.UserCoinID {
+			continue
+		}
+
+		date, err := helpers.ParseDateTime(transaction.DateTransaction)
+
+		if err != nil {
+			continue
+		}
+
+		day := date.Format(SnapshotDateFormat)
+		amount := transaction.Amount
+
+		if transaction.Type == "sell" {
+			amount = -amount
+		} else {
+			ledgerInvested += transaction.Total + transaction.Fee
+		}
+
+		amounts[day] += amount
+		flows[day] += transactionFlow(transaction)
+		ledgerAmount += amount
+
+		if dayOf(date).Before(start) {
+			start = dayOf(date)
+		}
+	}
+
+	if opening := coin.Amount - ledgerAmount; opening > 1e-9 {
+		if added, err := helpers.ParseDateTime(coin.DateAdded); err == nil {
+			day := added.Format(SnapshotDateFormat)
+
+			amounts[day] += opening
+			flows[day] += math.Max(coin.Invested-ledgerInvested, 0)
+
+			if dayOf(added).Before(start) {
+				start = dayOf(added)
+			}
+		}
+	}
+
+	coinID := GetCoinIDBySymbol(DB, coin.Symbol)
+
+	// Values before the first stored price are unknown, the holding then starts with its value on that day
+	priceStart := clampToHistory(DB, []int{coinID}, start)
+
+	days := []time.Time{}
+
+	for day := priceStart; !day.After(dayOf(now)); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	prices, ok := dailyCoinPrices(DB, coinID, days)
+
+	amount := 0.0
+
+	for day := start; day.Before(priceStart); day = day.AddDate(0, 0, 1) {
+		amount += amounts[day.Format(SnapshotDateFormat)]
+	}
+
+	points := []valuePoint{{Date: start.AddDate(0, 0, -1)}}
+
+	for i, day := range days {
+		price := coin.PriceEur
+
+		if ok {
+			price = prices[i]
+		}
+
+		amount += amounts[day.Format(SnapshotDateFormat)]
+
+		point := valuePoint{Date: day, Value: amount * price, Flow: flows[day.Format(SnapshotDateFormat)]}
+
+		if i == 0 && priceStart.After(start) {
+			point.Flow = 0
+			points = points[:0]
+		}
+
+		points = append(points, point)
+	}
+
+	// Today's value is the synced one
+	points[len(points)-1].Value = coin.Worth
+
+	return points
+}
+
+// dailyCoinPrices - price of the coin at the end of each day, the latest stored price before it is used on days without one
+// Second return value is false when price history of the coin starts after the first day, later prices are never used
+// for earlier days. Use clampToHistory to start the days where the history does.
+func dailyCoinPrices(DB *sql.DB, coinID int, days []time.Time) ([]float64, bool) {
+	if len(days) == 0 {
+		return []float64{}, true
+	}
+
+	previous, hasPrevious := getClosestCoinPrice(DB, "SELECT priceeur, date_price FROM coinprices WHERE coinid = $1 AND date_price < $2 ORDER BY date_price DESC LIMIT 1",
+		coinID, helpers.FormatDateTime(days[0]))
+
+	price := previous.PriceEur
+
+	history := GetCoinPriceHistory(DB, coinID, days[0], days[len(days)-1].AddDate(0, 0, 1))
+	historyIndex := 0
+
+	prices := make([]float64, len(days))
+
+	for i, day := range days {
+		endOfDay := day.AddDate(0, 0, 1).Format(SnapshotDateFormat)
+
+		// Last stored price of the day
+		for historyIndex < len(history) && history[historyIndex].DatePrice < endOfDay {
+			price = history[historyIndex].PriceEur
+			historyIndex++
+		}
+
+		if i == 0 && !hasPrevious && historyIndex == 0 {
+			return nil, false
+		}
+
+		prices[i] = price
+	}
+
+	return prices, true
+}
+
+// clampToHistory - later of from and the first day all the coins have price history, coins without any history are left out
+func clampToHistory(DB *sql.DB, coinIDs []int, from time.Time) time.Time {
+	for _, coinID := range coinIDs {
+		if start, ok := GetCoinHistoryStart(DB, coinID); ok && start.After(from) {
+			from = start
+		}
+	}
+
+	return from
+}
+
+// periodReturns - returns of the value points that fall into the period
+// Value on the last day before the period is the starting value.
+func periodReturns(points []valuePoint, period string, from time.Time) PerformanceReturns {
+	returns := PerformanceReturns{Period: period}
+
+	if len(points) == 0 {
+		return returns
+	}
+
+	base := 0
+
+	for i, point := range points {
+		if point.Date.After(from) {
+			break
+		}
+
+		base = i
+	}
+
+	points = points[base:]
+
+	first := points[0]
+	last := points[len(points)-1]
+
+	returns.From = first.Date.Format(SnapshotDateFormat)
+	returns.To = last.Date.Format(SnapshotDateFormat)
+	returns.StartValue = roundNumber(first.Value)
+	returns.EndValue = roundNumber(last.Value)
+
+	flows := []cashFlow{{Date: first.Date, Amount: -first.Value}}
+
+	for _, point := range points[1:] {
+		returns.NetFlows += point.Flow
+
+		flows = append(flows, cashFlow{Date: point.Date, Amount: -point.Flow})
+	}
+
+	flows = append(flows, cashFlow{Date: last.Date, Amount: last.Value})
+
+	returns.NetFlows = roundNumber(returns.NetFlows)
+
+	twr := timeWeightedReturn(points)
+
+	returns.TimeWeightedReturn = roundNumber(twr * 100)
+
+	if irr, ok := xirr(flows); ok {
+		irr = roundNumber(irr * 100)
+
+		returns.MoneyWeightedReturn = &irr
+	}
+
+	if days := last.Date.Sub(first.Date).Hours() / 24; days >= 365 && twr > -1 {
+		annualized := roundNumber((math.Pow(1+twr, 365/days) - 1) * 100)
+
+		returns.AnnualizedReturn = &annualized
+	}
+
+	return returns
+}
+
+// timeWeightedReturn - chain daily returns, money moved during a day counts as moved at its start
+func timeWeightedReturn(points []valuePoint) float64 {
+	growth := 1.0
+
+	for i := 1; i < len(points); i++ {
+		invested := points[i-1].Value + points[i].Flow
+
+		if invested <= 0 {
+			continue
+		}
+
+		growth *= 1 + (points[i].Value-invested)/invested
+	}
+
+	return growth - 1
+}
+
+// xirr - annual rate at which the cash flows are worth nothing today, false if there is no such rate
+func xirr(flows []cashFlow) (float64, bool) {
+	paid, received := false, false
+
+	for _, flow := range flows {
+		paid = paid || flow.Amount < 0
+		received = received || flow.Amount > 0
+	}
+
+	if !paid || !received {
+		return 0, false
+	}
+
+	sort.SliceStable(flows, func(i, j int) bool {
+		return flows[i].Date.Before(flows[j].Date)
+	})
+
+	npv := func(rate float64) float64 {
+		value := 0.0
+
+		for _, flow := range flows {
+			years := flow.Date.Sub(flows[0].Date).Hours() / 24 / 365
+
+			value += flow.Amount / math.Pow(1+rate, years)
+		}
+
+		return value
+	}
+
+	low, high := -0.9999, 1.0
+
+	// Widen the range until the value changes sign, very short periods can have huge annual rates
+	for npv(low)*npv(high) > 0 {
+		high *= 10
+
+		if high > 1e9 {
+			return 0, false
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		middle := (low + high) / 2
+
+		if npv(low)*npv(middle) <= 0 {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+
+	return (low + high) / 2, true
+}
+
+// transactionFlow - money put into the holding by a buy, or taken out by a sell
+func transactionFlow(transaction Transaction) float64 {
+	if transaction.Type == "sell" {
+		return -(transaction.Total - transaction.Fee)
+	}
+
+	return transaction.Total + transaction.Fee
+}
+
+// dayOf - date without time, in UTC like parsed snapshot dates
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// day - UTC day used by the tests, like parsed snapshot dates
+func day(year int, month time.Month, date int) time.Time {
+	return time.Date(year, month, date, 0, 0, 0, 0, time.UTC)
+}
+
+func TestXIRR(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []cashFlow
+		rate  float64
+		ok    bool
+	}{
+		{
+			name:  "ten percent over one year",
+			flows: []cashFlow{{Date: day(2021, 1, 1), Amount: -1000}, {Date: day(2022, 1, 1), Amount: 1100}},
+			rate:  0.1,
+			ok:    true,
+		},
+		{
+			name:  "ten percent a year over two years",
+			flows: []cashFlow{{Date: day(2021, 1, 1), Amount: -1000}, {Date: day(2023, 1, 1), Amount: 1210}},
+			rate:  0.1,
+			ok:    true,
+		},
+		{
+			name:  "loss over one year",
+			flows: []cashFlow{{Date: day(2021, 1, 1), Amount: -1000}, {Date: day(2022, 1, 1), Amount: 900}},
+			rate:  -0.1,
+			ok:    true,
+		},
+		{
+			name:  "flows out of order",
+			flows: []cashFlow{{Date: day(2022, 1, 1), Amount: 1100}, {Date: day(2021, 1, 1), Amount: -1000}},
+			rate:  0.1,
+			ok:    true,
+		},
+		{
+			name:  "nothing received",
+			flows: []cashFlow{{Date: day(2021, 1, 1), Amount: -1000}, {Date: day(2022, 1, 1), Amount: -100}},
+			ok:    false,
+		},
+		{
+			name:  "no flows",
+			flows: []cashFlow{},
+			ok:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rate, ok := xirr(test.flows)
+
+			if ok != test.ok {
+				t.Fatalf("xirr() ok = %v, want %v", ok, test.ok)
+			}
+
+			if ok && math.Abs(rate-test.rate) > 1e-6 {
+				t.Errorf("xirr() = %v, want %v", rate, test.rate)
+			}
+		})
+	}
+}
+
+func TestTimeWeightedReturn(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []valuePoint
+		want   float64
+	}{
+		{
+			name:   "no points",
+			points: []valuePoint{},
+			want:   0,
+		},
+		{
+			name:   "growth without flows",
+			points: []valuePoint{{Date: day(2021, 1, 1), Value: 100}, {Date: day(2021, 1, 2), Value: 110}},
+			want:   0.1,
+		},
+		{
+			name: "money put in does not count as return",
+			points: []valuePoint{
+				{Date: day(2021, 1, 1), Value: 100},
+				{Date: day(2021, 1, 2), Value: 110},
+				{Date: day(2021, 1, 3), Value: 165, Flow: 50},
+			},
+			want: 1.1*165/160 - 1,
+		},
+		{
+			name: "days with nothing invested are skipped",
+			points: []valuePoint{
+				{Date: day(2021, 1, 1), Value: 0},
+				{Date: day(2021, 1, 2), Value: 0},
+				{Date: day(2021, 1, 3), Value: 120, Flow: 100},
+			},
+			want: 0.2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := timeWeightedReturn(test.points); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("timeWeightedReturn() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPeriodReturns(t *testing.T) {
+	tests := []struct {
+		name       string
+		points     []valuePoint
+		from       time.Time
+		want       PerformanceReturns
+		irr        *float64
+		annualized *float64
+	}{
+		{
+			name:   "no points",
+			points: []valuePoint{},
+			want:   PerformanceReturns{Period: "all"},
+		},
+		{
+			name:       "one year without flows",
+			points:     []valuePoint{{Date: day(2021, 1, 1), Value: 1000}, {Date: day(2022, 1, 1), Value: 1100}},
+			want:       PerformanceReturns{Period: "all", From: "2021.01.01", To: "2022.01.01", StartValue: 1000, EndValue: 1100, TimeWeightedReturn: 10},
+			irr:        floatPointer(10),
+			annualized: floatPointer(10),
+		},
+		{
+			name: "period starts on the last day before it",
+			points: []valuePoint{
+				{Date: day(2021, 1, 1), Value: 500},
+				{Date: day(2021, 5, 31), Value: 1000},
+				{Date: day(2021, 6, 10), Value: 1500, Flow: 300},
+			},
+			from: day(2021, 6, 1),
+			want: PerformanceReturns{Period: "all", From: "2021.05.31", To: "2021.06.10", StartValue: 1000, EndValue: 1500, NetFlows: 300, TimeWeightedReturn: 15.38},
+			irr:  floatPointer(77545.36),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := periodReturns(test.points, "all", test.from)
+
+			if (got.MoneyWeightedReturn == nil) != (test.irr == nil) || (got.MoneyWeightedReturn != nil && *got.MoneyWeightedReturn != *test.irr) {
+				t.Errorf("periodReturns() money-weighted return = %v, want %v", got.MoneyWeightedReturn, test.irr)
+			}
+
+			if (got.AnnualizedReturn == nil) != (test.annualized == nil) || (got.AnnualizedReturn != nil && *got.AnnualizedReturn != *test.annualized) {
+				t.Errorf("periodReturns() annualized return = %v, want %v", got.AnnualizedReturn, test.annualized)
+			}
+
+			got.MoneyWeightedReturn, got.AnnualizedReturn = nil, nil
+
+			if got != test.want {
+				t.Errorf("periodReturns() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+// floatPointer - pointer to the value, for optional fields
+func floatPointer(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,415 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// PlanSchedules - how often a recurring plan buys
+var PlanSchedules = []string{"daily", "weekly", "monthly"}
+
+// PlanModes - automatic plans add buys to the ledger right away, review plans keep them pending until approved
+var PlanModes = []string{"automatic", "review"}
+
+// Plan - recurring purchase of a fixed EUR amount of a coin, fee is taken out of the amount
+// Missed runs are not made up for, the next run is always the first scheduled one after the last run.
+type Plan struct {
+	PlanID      int     `json:"planid"`
+	OwnerID     int     `json:"ownerid"`
+	PortfolioID int     `json:"portfolioid"`
+	Name        string  `json:"name"`
+	Symbol      string  `json:"symbol"`
+	AmountEur   float64 `json:"amount"`
+	Fee         float64 `json:"fee"`
+	Schedule    string  `json:"schedule"`
+	Lives       string  `json:"lives"`
+	Mode        string  `json:"mode"`
+	Enabled     bool    `json:"enabled"`
+	NextRun     string  `json:"next_run"`
+	LastRun     string  `json:"last_run"`
+	DateAdded   string  `json:"date_added"`
+}
+
+// PlanBuy - buy generated by a plan run at the synced price, pending ones wait for review
+type PlanBuy struct {
+	PlanBuyID     int     `json:"planbuyid"`
+	PlanID        int     `json:"planid"`
+	PortfolioID   int     `json:"portfolioid"`
+	Name          string  `json:"name"`
+	Symbol        string  `json:"symbol"`
+	Amount        float64 `json:"amount"`
+	PriceEur      float64 `json:"priceeur"`
+	Fee           float64 `json:"fee"`
+	Total         float64 `json:"total"`
+	Lives         string  `json:"lives"`
+	Status        string  `json:"status"`
+	TransactionID int     `json:"transactionid"`
+	DateRun       string  `json:"date_run"`
+	DateReviewed  string  `json:"date_reviewed"`
+}
+
+// planColumns - plan columns in the order they are scanned by scanPlan
+const planColumns = "planid, userid, portfolioid, name, symbol, amount, fee, schedule, lives, mode, enabled, next_run, last_run, date_added"
+
+// planBuyColumns - plan buy columns in the order they are scanned by scanPlanBuy
+const planBuyColumns = "planbuyid, planid, portfolioid, name, symbol, amount, priceeur, fee, total, lives, status, transactionid, date_run, date_reviewed"
+
+// ValidatePlan - check plan amount, schedule and mode
+func ValidatePlan(plan Plan) error {
+	if plan.AmountEur <= 0 {
+		return errors.New("Amount has to be greater than 0.")
+	}
+
+	if plan.Fee < 0 || plan.Fee >= plan.AmountEur {
+		return errors.New("Fee can not be negative or take the whole amount.")
+	}
+
+	if indexOf(PlanSchedules, plan.Schedule) < 0 {
+		return errors.New("Schedule has to be one of: " + strings.Join(PlanSchedules, ", ") + ".")
+	}
+
+	if indexOf(PlanModes, plan.Mode) < 0 {
+		return errors.New("Mode has to be one of: " + strings.Join(PlanModes, ", ") + ".")
+	}
+
+	if _, err := helpers.ParseDateTime(plan.NextRun); err != nil {
+		return errors.New("Please provide a valid date of the next run.")
+	}
+
+	return nil
+}
+
+// ValidateNewPlan - check plan like ValidatePlan, new plans can not be scheduled in the past
+func ValidateNewPlan(plan Plan) error {
+	if err := ValidatePlan(plan); err != nil {
+		return err
+	}
+
+	nextRun, _ := helpers.ParseDateTime(plan.NextRun)
+
+	if nextRun.Before(time.Now().Add(-time.Minute)) {
+		return errors.New("Next run can not be in the past.")
+	}
+
+	return nil
+}
+
+// planNextRun - next run of the plan in the format dates are stored in, so due plans can be compared as text
+func planNextRun(plan Plan) string {
+	nextRun, err := helpers.ParseDateTime(plan.NextRun)
+
+	if err != nil {
+		return plan.NextRun
+	}
+
+	return helpers.FormatDateTime(nextRun)
+}
+
+// CreatePlan - create new recurring plan in owner's portfolio
+func CreatePlan(DB queryer, plan Plan) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO plans(userid, portfolioid, name, symbol, amount, fee, schedule, lives, mode, enabled, next_run, date_added) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) returning planid;",
+		plan.OwnerID, plan.PortfolioID, plan.Name, plan.Symbol, plan.AmountEur, plan.Fee, plan.Schedule, plan.Lives, plan.Mode, plan.Enabled, planNextRun(plan), helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// UpdatePlan - update plan settings
+func UpdatePlan(DB *sql.DB, plan Plan) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE plans SET amount = $1, fee = $2, schedule = $3, lives = $4, mode = $5, enabled = $6, next_run = $7 WHERE planid = $8 AND userid = $9 returning planid;",
+		plan.AmountEur, plan.Fee, plan.Schedule, plan.Lives, plan.Mode, plan.Enabled, planNextRun(plan), plan.PlanID, plan.OwnerID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// RemovePlan - delete plan and its pending buys, reviewed buys stay as history of the ledger
+func RemovePlan(DB *sql.DB, planID int) bool {
+	result, err := DB.Exec("DELETE FROM plans WHERE planid = $1", planID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if removed, _ := result.RowsAffected(); removed < 1 {
+		return false
+	}
+
+	_, err = DB.Exec("DELETE FROM planbuys WHERE planid = $1 AND status = $2", planID, "pending")
+
+	return err == nil
+}
+
+// GetPlan - get single plan, PlanID is 0 if it does not exist
+func GetPlan(DB *sql.DB, planID int) Plan {
+	var plan Plan
+
+	err := scanPlan(DB.QueryRow("SELECT "+planColumns+" FROM plans WHERE planid = $1", planID), &plan)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return plan
+}
+
+// GetPortfolioPlans - get plans of owner's portfolio, AllPortfolios returns plans of every portfolio
+func GetPortfolioPlans(DB queryer, ownerID int, portfolioID int) []Plan {
+	return queryPlans(DB, "SELECT "+planColumns+" FROM plans WHERE userid = $1 AND ($2 = 0 OR portfolioid = $2) ORDER BY planid", ownerID, portfolioID)
+}
+
+// GetPlanBuy - get single plan buy, PlanBuyID is 0 if it does not exist
+func GetPlanBuy(DB *sql.DB, planBuyID int) PlanBuy {
+	var planBuy PlanBuy
+
+	err := scanPlanBuy(DB.QueryRow("SELECT "+planBuyColumns+" FROM planbuys WHERE planbuyid = $1", planBuyID), &planBuy)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return planBuy
+}
+
+// GetPortfolioPlanBuys - buys generated by plans of owner's portfolio, newest first
+func GetPortfolioPlanBuys(DB *sql.DB, ownerID int, portfolioID int, limit int) []PlanBuy {
+	rows, err := DB.Query("SELECT "+planBuyColumns+" FROM planbuys WHERE userid = $1 AND ($2 = 0 OR portfolioid = $2) ORDER BY date_run DESC, planbuyid DESC LIMIT $3", ownerID, portfolioID, limit)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	planBuys := []PlanBuy{}
+
+	// Foreach plan buy
+	for rows.Next() {
+		var planBuy PlanBuy
+
+		if err = scanPlanBuy(rows, &planBuy); err != nil {
+			panic(err)
+		}
+
+		planBuys = append(planBuys, planBuy)
+	}
+
+	return planBuys
+}
+
+// GetPlanBuyPortfolio - owner and portfolio of the plan buy, both 0 if it does not exist
+func GetPlanBuyPortfolio(DB *sql.DB, planBuyID int) (int, int) {
+	var ownerID, portfolioID int
+
+	err := DB.QueryRow("SELECT userid, portfolioid FROM planbuys WHERE planbuyid = $1", planBuyID).Scan(&ownerID, &portfolioID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return ownerID, portfolioID
+}
+
+// RunPlans - run plans that are due
+func RunPlans(minutes int, DB *sql.DB) {
+	d := time.Duration(minutes) * time.Minute
+
+	for range time.Tick(d) {
+		for _, plan := range queryPlans(DB, "SELECT "+planColumns+" FROM plans WHERE enabled = true AND next_run <= $1 ORDER BY next_run", helpers.GetCurrentDateTime()) {
+			RunPlan(DB, plan)
+		}
+	}
+}
+
+// RunPlan - generate plan's buy at the synced price and schedule the next run
+// Plans of coins without a synced price are tried again on the next tick.
+func RunPlan(DB *sql.DB, plan Plan) {
+	_, priceEur := GetCoinBySymbol(DB, plan.Symbol)
+
+	if priceEur <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	planBuy := PlanBuy{
+		PlanID:      plan.PlanID,
+		PortfolioID: plan.PortfolioID,
+		Name:        plan.Name,
+		Symbol:      plan.Symbol,
+		Amount:      (plan.AmountEur - plan.Fee) / priceEur,
+		PriceEur:    priceEur,
+		Fee:         plan.Fee,
+		Total:       plan.AmountEur - plan.Fee,
+		Lives:       plan.Lives,
+		Status:      "pending",
+		DateRun:     helpers.FormatDateTime(now),
+	}
+
+	err := DB.QueryRow("INSERT INTO planbuys(userid, planid, portfolioid, name, symbol, amount, priceeur, fee, total, lives, status, date_run) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) returning planbuyid;",
+		plan.OwnerID, planBuy.PlanID, planBuy.PortfolioID, planBuy.Name, planBuy.Symbol, planBuy.Amount, planBuy.PriceEur, planBuy.Fee, planBuy.Total, planBuy.Lives,
+		planBuy.Status, planBuy.DateRun).Scan(&planBuy.PlanBuyID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	nextRun, err := helpers.ParseDateTime(plan.NextRun)
+
+	if err != nil {
+		nextRun = now
+	}
+
+	_, err = DB.Exec("UPDATE plans SET next_run = $1, last_run = $2 WHERE planid = $3",
+		helpers.FormatDateTime(nextPlanRun(plan.Schedule, nextRun, now)), planBuy.DateRun, plan.PlanID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if plan.Mode == "automatic" {
+		ApprovePlanBuy(DB, plan.OwnerID, planBuy)
+	}
+}
+
+// ApprovePlanBuy - add pending buy to the ledger, returns transaction ID or 0 if it could not be added
+// The buy is claimed and added in one DB transaction, so a buy approved twice at once reaches the ledger only once.
+func ApprovePlanBuy(DB *sql.DB, ownerID int, planBuy PlanBuy) int {
+	transaction := Transaction{
+		PortfolioID:     planBuy.PortfolioID,
+		Type:            "buy",
+		Name:            planBuy.Name,
+		Symbol:          planBuy.Symbol,
+		Amount:          planBuy.Amount,
+		PriceEur:        planBuy.PriceEur,
+		Fee:             planBuy.Fee,
+		Total:           planBuy.Total,
+		Lives:           planBuy.Lives,
+		Source:          "plan",
+		DateTransaction: planBuy.DateRun,
+	}
+
+	transaction.Hash = NewTransactionHash(transaction)
+
+	tx, err := DB.Begin()
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer tx.Rollback()
+
+	if reviewPlanBuy(tx, planBuy.PlanBuyID, "approved", 0) < 1 {
+		return 0
+	}
+
+	transactionID := applyTransaction(tx, ownerID, transaction)
+
+	if transactionID < 1 {
+		return 0
+	}
+
+	_, err = tx.Exec("UPDATE planbuys SET transactionid = $1 WHERE planbuyid = $2", transactionID, planBuy.PlanBuyID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		panic(err)
+	}
+
+	NotifyUser(DB, ownerID, "holding.changed", HoldingChangedEvent{Action: "buy", Symbol: planBuy.Symbol, Count: 1})
+
+	return transactionID
+}
+
+// RejectPlanBuy - mark pending buy as rejected, it never reaches the ledger
+func RejectPlanBuy(DB *sql.DB, planBuyID int) int {
+	return reviewPlanBuy(DB, planBuyID, "rejected", 0)
+}
+
+// reviewPlanBuy - set status of a pending buy, returns 0 if the buy is not pending anymore
+func reviewPlanBuy(DB queryer, planBuyID int, status string, transactionID int) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE planbuys SET status = $1, transactionid = $2, date_reviewed = $3 WHERE planbuyid = $4 AND status = $5 returning planbuyid;",
+		status, transactionID, helpers.GetCurrentDateTime(), planBuyID, "pending").Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// nextPlanRun - first scheduled run after now, counted from the last scheduled run
+func nextPlanRun(schedule string, last time.Time, now time.Time) time.Time {
+	next := last
+
+	for !next.After(now) {
+		switch schedule {
+		case "daily":
+			next = next.AddDate(0, 0, 1)
+		case "weekly":
+			next = next.AddDate(0, 0, 7)
+		default:
+			next = next.AddDate(0, 1, 0)
+		}
+	}
+
+	return next
+}
+
+// queryPlans - plans returned by the query
+func queryPlans(DB queryer, query string, args ...interface{}) []Plan {
+	rows, err := DB.Query(query, args...)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	plans := []Plan{}
+
+	// Foreach plan
+	for rows.Next() {
+		var plan Plan
+
+		if err = scanPlan(rows, &plan); err != nil {
+			panic(err)
+		}
+
+		plans = append(plans, plan)
+	}
+
+	return plans
+}
+
+// scanPlan - scan planColumns into plan
+func scanPlan(row interface{ Scan(...interface{}) error }, plan *Plan) error {
+	return row.Scan(&plan.PlanID, &plan.OwnerID, &plan.PortfolioID, &plan.Name, &plan.Symbol, &plan.AmountEur, &plan.Fee, &plan.Schedule, &plan.Lives,
+		&plan.Mode, &plan.Enabled, &plan.NextRun, &plan.LastRun, &plan.DateAdded)
+}
+
+// scanPlanBuy - scan planBuyColumns into plan buy
+func scanPlanBuy(row interface{ Scan(...interface{}) error }, planBuy *PlanBuy) error {
+	return row.Scan(&planBuy.PlanBuyID, &planBuy.PlanID, &planBuy.PortfolioID, &planBuy.Name, &planBuy.Symbol, &planBuy.Amount, &planBuy.PriceEur, &planBuy.Fee,
+		&planBuy.Total, &planBuy.Lives, &planBuy.Status, &planBuy.TransactionID, &planBuy.DateRun, &planBuy.DateReviewed)
+}
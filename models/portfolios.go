@@ -0,0 +1,298 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// AllPortfolios - portfolio ID meaning every portfolio of the user combined
+// Snapshots with this ID hold the combined totals, same as snapshots taken before portfolios existed.
+const AllPortfolios = 0
+
+// DefaultPortfolioName - name of the portfolio created for users that have none yet
+const DefaultPortfolioName = "Main"
+
+// Portfolio - separate set of holdings with its own base currency and public endpoint
+// Holdings and transactions of the portfolio are stored under the owner, Role is the role of the user viewing it.
+type Portfolio struct {
+	PortfolioID     int    `json:"portfolioid"`
+	OwnerID         int    `json:"ownerid"`
+	Role            string `json:"role"`
+	Name            string `json:"name"`
+	Currency        string `json:"currency"`
+	Endpoint        string `json:"endpoint"`
+	EndpointEnabled bool   `json:"endpoint_enabled"`
+	DateAdded       string `json:"date_added"`
+}
+
+// portfolioColumns - portfolio columns in the order they are scanned by scanPortfolio
+const portfolioColumns = "portfolioid, userid, name, currency, endpoint, endpoint_enabled, date_added"
+
+// ValidatePortfolio - check portfolio name and base currency
+func ValidatePortfolio(portfolio Portfolio) error {
+	if strings.TrimSpace(portfolio.Name) == "" || len(portfolio.Name) > 50 {
+		return errors.New("Portfolio name has to be between 1 and 50 characters.")
+	}
+
+	if !FXCurrencyExists(portfolio.Currency) {
+		return errors.New("Currency has to be one of: " + strings.Join(FXCurrencies, ", ") + ".")
+	}
+
+	return nil
+}
+
+// CreatePortfolio - add new portfolio with a fresh, disabled endpoint
+// User's first portfolio takes over holdings and transactions added before portfolios existed.
+func CreatePortfolio(DB queryer, userID int, portfolio Portfolio) int {
+	firstPortfolio := len(GetUserPortfolios(DB, userID)) == 0
+
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO portfolios(userid, name, currency, endpoint, endpoint_enabled, date_added) VALUES($1, $2, $3, $4, $5, $6) returning portfolioid;",
+		userID, portfolio.Name, portfolio.Currency, GenerateEndpoint(), false, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if firstPortfolio {
+		for _, query := range []string{
+			"UPDATE usercoins SET portfolioid = $1 WHERE userid = $2 AND portfolioid = 0",
+			"UPDATE transactions SET portfolioid = $1 WHERE userid = $2 AND portfolioid = 0",
+		} {
+			if _, err = DB.Exec(query, lastInsertID, userID); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return lastInsertID
+}
+
+// UpdatePortfolio - update portfolio name and base currency
+func UpdatePortfolio(DB *sql.DB, userID int, portfolio Portfolio) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE portfolios SET name = $1, currency = $2 WHERE portfolioid = $3 AND userid = $4 returning portfolioid;",
+		portfolio.Name, portfolio.Currency, portfolio.PortfolioID, userID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// RemovePortfolio - delete empty portfolio with its snapshots, members and invitations
+func RemovePortfolio(DB *sql.DB, userID int, portfolioID int) bool {
+	result, err := DB.Exec("DELETE FROM portfolios WHERE portfolioid = $1 AND userid = $2", portfolioID, userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if removed, _ := result.RowsAffected(); removed < 1 {
+		return false
+	}
+
+	for _, query := range []string{
+		"DELETE FROM snapshots WHERE portfolioid = $1",
+		"DELETE FROM portfoliomembers WHERE portfolioid = $1",
+		"DELETE FROM portfolioinvitations WHERE portfolioid = $1",
+		"DELETE FROM plans WHERE portfolioid = $1",
+		"DELETE FROM planbuys WHERE portfolioid = $1",
+	} {
+		if _, err = DB.Exec(query, portfolioID); err != nil {
+			panic(err)
+		}
+	}
+
+	return true
+}
+
+// PortfolioIsEmpty - check if portfolio has no holdings and no transactions
+func PortfolioIsEmpty(DB *sql.DB, portfolioID int) bool {
+	count := 0
+
+	row := DB.QueryRow("SELECT (SELECT COUNT(*) FROM usercoins WHERE portfolioid = $1) + (SELECT COUNT(*) FROM transactions WHERE portfolioid = $1)", portfolioID)
+
+	err := row.Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count == 0
+}
+
+// GetUserPortfolio - get user's own portfolio, PortfolioID is 0 if the user does not own it
+func GetUserPortfolio(DB queryer, userID int, portfolioID int) Portfolio {
+	var portfolio Portfolio
+
+	err := scanPortfolio(DB.QueryRow("SELECT "+portfolioColumns+" FROM portfolios WHERE portfolioid = $1 AND userid = $2", portfolioID, userID), &portfolio)
+
+	if err == sql.ErrNoRows {
+		return portfolio
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	portfolio.Role = "owner"
+
+	return portfolio
+}
+
+// GetUserPortfolios - get all portfolios the user owns, default one first
+func GetUserPortfolios(DB queryer, userID int) []Portfolio {
+	rows, err := DB.Query("SELECT "+portfolioColumns+" FROM portfolios WHERE userid = $1 ORDER BY portfolioid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var portfolios []Portfolio
+
+	// Foreach portfolio
+	for rows.Next() {
+		var portfolio Portfolio
+
+		if err = scanPortfolio(rows, &portfolio); err != nil {
+			panic(err)
+		}
+
+		portfolio.Role = "owner"
+
+		portfolios = append(portfolios, portfolio)
+	}
+
+	return portfolios
+}
+
+// GetDefaultPortfolio - user's oldest portfolio, PortfolioID is 0 when the user has none yet
+func GetDefaultPortfolio(DB queryer, userID int) Portfolio {
+	portfolios := GetUserPortfolios(DB, userID)
+
+	if len(portfolios) > 0 {
+		return portfolios[0]
+	}
+
+	return Portfolio{}
+}
+
+// CreateDefaultPortfolio - user's oldest portfolio, created when the user has none yet
+// Creating it moves holdings and transactions added before portfolios existed into it.
+func CreateDefaultPortfolio(DB queryer, userID int) Portfolio {
+	if portfolio := GetDefaultPortfolio(DB, userID); portfolio.PortfolioID > 0 {
+		return portfolio
+	}
+
+	portfolioID := CreatePortfolio(DB, userID, Portfolio{Name: DefaultPortfolioName, Currency: "EUR"})
+
+	return GetUserPortfolio(DB, userID, portfolioID)
+}
+
+// MigrateDefaultPortfolios - create default portfolio for users registered before portfolios existed
+func MigrateDefaultPortfolios(DB *sql.DB) {
+	rows, err := DB.Query("SELECT userid FROM users WHERE userid NOT IN (SELECT userid FROM portfolios) ORDER BY userid")
+
+	if err != nil {
+		panic(err)
+	}
+
+	var userIDs []int
+
+	// Foreach user
+	for rows.Next() {
+		var userID int
+
+		if err = rows.Scan(&userID); err != nil {
+			panic(err)
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	rows.Close()
+
+	for _, userID := range userIDs {
+		CreateDefaultPortfolio(DB, userID)
+	}
+}
+
+// GetUserCurrency - base currency of user's default portfolio, EUR when the user has no portfolio yet
+func GetUserCurrency(DB *sql.DB, userID int) string {
+	currency := "EUR"
+
+	err := DB.QueryRow("SELECT currency FROM portfolios WHERE userid = $1 ORDER BY portfolioid LIMIT 1", userID).Scan(&currency)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return currency
+}
+
+// AllPortfoliosView - combined view of every portfolio the user owns, values are in EUR
+func AllPortfoliosView(userID int) Portfolio {
+	return Portfolio{PortfolioID: AllPortfolios, OwnerID: userID, Role: "owner", Name: "All portfolios", Currency: "EUR"}
+}
+
+// PortfolioEndpointAction - enable, disable or regenerate portfolio's public endpoint
+func PortfolioEndpointAction(DB *sql.DB, userID int, portfolioID int, action string) bool {
+	query := "UPDATE portfolios SET endpoint_enabled = $1 WHERE portfolioid = $2 AND userid = $3"
+	value := interface{}(action == "enable")
+
+	if action == "refresh" {
+		query = "UPDATE portfolios SET endpoint = $1 WHERE portfolioid = $2 AND userid = $3"
+		value = GenerateEndpoint()
+	}
+
+	result, err := DB.Exec(query, value, portfolioID, userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	updated, _ := result.RowsAffected()
+
+	return updated > 0
+}
+
+// PortfolioByEndpoint - portfolio of an enabled endpoint, PortfolioID is 0 if there is none
+func PortfolioByEndpoint(DB *sql.DB, endpoint string) Portfolio {
+	var portfolio Portfolio
+
+	err := scanPortfolio(DB.QueryRow("SELECT "+portfolioColumns+" FROM portfolios WHERE endpoint = $1 AND endpoint_enabled = true", endpoint), &portfolio)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return portfolio
+}
+
+// GetCoinPortfolio - owner and portfolio of the holding, owner is 0 if the holding does not exist
+func GetCoinPortfolio(DB *sql.DB, userCoinID string) (int, int) {
+	var ownerID, portfolioID int
+
+	err := DB.QueryRow("SELECT userid, portfolioid FROM usercoins WHERE usercoinid = $1", userCoinID).Scan(&ownerID, &portfolioID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return ownerID, portfolioID
+}
+
+// scanPortfolio - scan portfolioColumns into portfolio
+func scanPortfolio(row interface{ Scan(...interface{}) error }, portfolio *Portfolio) error {
+	return row.Scan(&portfolio.PortfolioID, &portfolio.OwnerID, &portfolio.Name, &portfolio.Currency, &portfolio.Endpoint, &portfolio.EndpointEnabled, &portfolio.DateAdded)
+}
@@ -0,0 +1,226 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// PriceHistoryInterval - how often coin prices from CMC are stored in price history
+const PriceHistoryInterval = time.Hour
+
+// PriceHistoryTopCoins - coins with the largest market cap always kept in price history, for benchmarks and backtests
+// Other coins are only kept while someone holds, watches or has an alert on them.
+const PriceHistoryTopCoins = 100
+
+// priceHistoryMaxGap - how far a time can be from the closest stored price
+// Prices are stored on the first CMC sync after each interval, so samples can be a sync more than an interval apart.
+const priceHistoryMaxGap = PriceHistoryInterval * 3 / 2
+
+// CoinPrice - coin price at a point in time
+type CoinPrice struct {
+	PriceEur  float64 `json:"priceeur"`
+	DatePrice string  `json:"date_price"`
+}
+
+// RecordCoinPrice - store current coin price in price history
+func RecordCoinPrice(DB *sql.DB, coinID int, priceEur float64) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO coinprices(coinid, priceeur, date_price) VALUES($1, $2, $3) returning coinpriceid;",
+		coinID, priceEur, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// GetCoinPriceAt - coin price at given time from price history
+// Price is interpolated between the closest stored prices before and after the time. Second return value
+// is false when the time is before the first stored price or further than priceHistoryMaxGap from the
+// closest one, as history only starts when the coin was first recorded and may have gaps.
+func GetCoinPriceAt(DB *sql.DB, coinID int, at time.Time) (float64, bool) {
+	before, hasBefore, after, hasAfter := getCoinPricesAround(DB, coinID, at)
+
+	if !hasBefore {
+		return 0, false
+	}
+
+	beforeTime, _ := helpers.ParseDateTime(before.DatePrice)
+
+	gap := at.Sub(beforeTime)
+
+	if hasAfter {
+		afterTime, _ := helpers.ParseDateTime(after.DatePrice)
+
+		if afterTime.Sub(at) < gap {
+			gap = afterTime.Sub(at)
+		}
+	}
+
+	if gap > priceHistoryMaxGap {
+		return 0, false
+	}
+
+	return interpolateCoinPrice(before, hasBefore, after, hasAfter, at), true
+}
+
+// GetCoinHistoryStart - day of the first stored price of the coin, false when the coin has no price history
+func GetCoinHistoryStart(DB *sql.DB, coinID int) (time.Time, bool) {
+	var first sql.NullString
+
+	err := DB.QueryRow("SELECT MIN(date_price) FROM coinprices WHERE coinid = $1", coinID).Scan(&first)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if !first.Valid {
+		return time.Time{}, false
+	}
+
+	date, err := helpers.ParseDateTime(first.String)
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return dayOf(date), true
+}
+
+// interpolateCoinPrice - price between the closest stored prices, if there is only one of them it is used as is
+func interpolateCoinPrice(before CoinPrice, hasBefore bool, after CoinPrice, hasAfter bool, at time.Time) float64 {
+	if !hasBefore {
+		return after.PriceEur
+	} else if !hasAfter {
+		return before.PriceEur
+	}
+
+	beforeTime, _ := helpers.ParseDateTime(before.DatePrice)
+	afterTime, _ := helpers.ParseDateTime(after.DatePrice)
+
+	if !afterTime.After(beforeTime) {
+		return before.PriceEur
+	}
+
+	ratio := float64(at.Sub(beforeTime)) / float64(afterTime.Sub(beforeTime))
+
+	return before.PriceEur + (after.PriceEur-before.PriceEur)*ratio
+}
+
+// getCoinPricesAround - closest stored prices of the coin before and after the time
+func getCoinPricesAround(DB *sql.DB, coinID int, at time.Time) (CoinPrice, bool, CoinPrice, bool) {
+	date := helpers.FormatDateTime(at)
+
+	before, hasBefore := getClosestCoinPrice(DB, "SELECT priceeur, date_price FROM coinprices WHERE coinid = $1 AND date_price <= $2 ORDER BY date_price DESC LIMIT 1", coinID, date)
+	after, hasAfter := getClosestCoinPrice(DB, "SELECT priceeur, date_price FROM coinprices WHERE coinid = $1 AND date_price >= $2 ORDER BY date_price LIMIT 1", coinID, date)
+
+	return before, hasBefore, after, hasAfter
+}
+
+// GetInvestedAtDate - cost of buying the amount of coin at given time, from price history
+func GetInvestedAtDate(DB *sql.DB, coinID int, amount float64, at time.Time) (float64, bool) {
+	price, ok := GetCoinPriceAt(DB, coinID, at)
+
+	if !ok {
+		return 0, false
+	}
+
+	return roundNumber(price * amount), true
+}
+
+// GetCoinPriceHistory - stored prices of the coin between two dates, oldest first
+func GetCoinPriceHistory(DB *sql.DB, coinID int, from time.Time, to time.Time) []CoinPrice {
+	rows, err := DB.Query("SELECT priceeur, date_price FROM coinprices WHERE coinid = $1 AND date_price >= $2 AND date_price <= $3 ORDER BY date_price",
+		coinID, helpers.FormatDateTime(from), helpers.FormatDateTime(to))
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var prices []CoinPrice
+
+	// Foreach price
+	for rows.Next() {
+		var price CoinPrice
+
+		err = rows.Scan(&price.PriceEur, &price.DatePrice)
+
+		if err != nil {
+			panic(err)
+		}
+
+		prices = append(prices, price)
+	}
+
+	return prices
+}
+
+// getClosestCoinPrice - run one of the closest price queries
+func getClosestCoinPrice(DB *sql.DB, query string, coinID int, date string) (CoinPrice, bool) {
+	var price CoinPrice
+
+	err := DB.QueryRow(query, coinID, date).Scan(&price.PriceEur, &price.DatePrice)
+
+	if err == sql.ErrNoRows {
+		return price, false
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	return price, true
+}
+
+// GetCoinByID - coin from the catalogue, empty name if it does not exist
+func GetCoinByID(DB *sql.DB, coinID int) CoinSymbolInfo {
+	var coin CoinSymbolInfo
+
+	err := DB.QueryRow("SELECT coinid, name, symbol FROM coins WHERE coinid = $1", coinID).Scan(&coin.CoinID, &coin.Name, &coin.Symbol)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return coin
+}
+
+// GetCoinIDBySymbol - ID of the coin with this symbol, 0 if it does not exist
+func GetCoinIDBySymbol(DB queryer, coinSymbol string) int {
+	coin, _ := findCoinBySymbol(DB, coinSymbol)
+
+	return coin.CoinID
+}
+
+// GetPriceHistoryCoinIDs - IDs of coins held, watched or with an alert, their prices are kept in history
+func GetPriceHistoryCoinIDs(DB *sql.DB) map[int]bool {
+	rows, err := DB.Query("SELECT c.coinid FROM coins c WHERE " + trackedCoinsCondition + " OR c.coinid IN (SELECT coinid FROM alerts)")
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	coinIDs := map[int]bool{}
+
+	// Foreach coin
+	for rows.Next() {
+		var coinID int
+
+		if err = rows.Scan(&coinID); err != nil {
+			panic(err)
+		}
+
+		coinIDs[coinID] = true
+	}
+
+	return coinIDs
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ProfitsSortFields - fields coins of the profits endpoint can be sorted by, change is the 24h price change
+var ProfitsSortFields = []string{"worth", "profit", "change", "name"}
+
+// ProfitsOptions - how coins of the profits endpoint are filtered, sorted and hidden
+// Coins worth less than MinWorth are hidden as dust; totals still count them when TotalsIncludeHidden is set.
+type ProfitsOptions struct {
+	Sort                string
+	Order               string
+	Location            string
+	Tag                 string
+	Symbols             []string
+	MinWorth            float64
+	TotalsIncludeHidden bool
+}
+
+// ProfitsView - coins left after filtering, their totals and number of coins hidden as dust
+type ProfitsView struct {
+	CoinData            []Coin   `json:"coindata"`
+	SyncData            SyncInfo `json:"syncdata"`
+	Hidden              int      `json:"hidden"`
+	TotalsIncludeHidden bool     `json:"totals_include_hidden"`
+}
+
+// ValidateProfitsOptions - check sort field, order and dust threshold
+func ValidateProfitsOptions(options ProfitsOptions) error {
+	if options.Sort != "" && indexOf(ProfitsSortFields, options.Sort) < 0 {
+		return errors.New("Coins can be sorted by: " + strings.Join(ProfitsSortFields, ", ") + ".")
+	}
+
+	if options.Order != "" && options.Order != "asc" && options.Order != "desc" {
+		return errors.New("Order has to be asc or desc.")
+	}
+
+	if options.MinWorth < 0 {
+		return errors.New("Dust threshold can not be negative.")
+	}
+
+	return nil
+}
+
+// ApplyProfitsOptions - filter coins by location, tag and symbol, hide dust and sort what is left
+// Without a sort field coins keep their order. Names are sorted A-Z and numbers largest first unless order says otherwise.
+func ApplyProfitsOptions(coins []Coin, options ProfitsOptions) ProfitsView {
+	if options.Tag != "" {
+		coins = FilterCoinsByTag(coins, options.Tag)
+	}
+
+	filtered := []Coin{}
+
+	for _, coin := range coins {
+		if options.Location != "" && !strings.EqualFold(coin.Lives, options.Location) {
+			continue
+		}
+
+		if len(options.Symbols) > 0 && !containsFold(options.Symbols, coin.Symbol) {
+			continue
+		}
+
+		filtered = append(filtered, coin)
+	}
+
+	view := ProfitsView{CoinData: []Coin{}, TotalsIncludeHidden: options.TotalsIncludeHidden}
+
+	for _, coin := range filtered {
+		if coin.Worth < options.MinWorth {
+			view.Hidden++
+
+			continue
+		}
+
+		view.CoinData = append(view.CoinData, coin)
+	}
+
+	if options.TotalsIncludeHidden {
+		view.SyncData = CalculateSyncInfo(filtered)
+	} else {
+		view.SyncData = CalculateSyncInfo(view.CoinData)
+	}
+
+	if options.Sort != "" {
+		sortCoins(view.CoinData, options.Sort, options.Order)
+	}
+
+	return view
+}
+
+// sortCoins - sort coins by the field, ties are sorted by name
+func sortCoins(coins []Coin, field string, order string) {
+	value := func(coin Coin) float64 {
+		switch field {
+		case "profit":
+			return coin.MadeLost
+		case "change":
+			return coin.Change24h
+		}
+
+		return coin.Worth
+	}
+
+	descending := order == "desc" || (order == "" && field != "name")
+
+	sort.SliceStable(coins, func(i, j int) bool {
+		if field != "name" && value(coins[i]) != value(coins[j]) {
+			return (value(coins[i]) > value(coins[j])) == descending
+		}
+
+		if field == "name" && !strings.EqualFold(coins[i].Name, coins[j].Name) {
+			return (strings.ToLower(coins[i].Name) > strings.ToLower(coins[j].Name)) == descending
+		}
+
+		return strings.ToLower(coins[i].Name) < strings.ToLower(coins[j].Name)
+	})
+}
+
+// containsFold - check if values contain the value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,228 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// ProjectionMaxMonths - longest horizon a projection can run for
+const ProjectionMaxMonths = 120
+
+// ProjectionMaxSimulations - most simulations a projection can run
+const ProjectionMaxSimulations = 10000
+
+// ProjectionBand - percentiles of simulated portfolio value at the end of a month
+type ProjectionBand struct {
+	Date        string  `json:"date"`
+	Contributed float64 `json:"contributed"`
+	P5          float64 `json:"p5"`
+	P25         float64 `json:"p25"`
+	P50         float64 `json:"p50"`
+	P75         float64 `json:"p75"`
+	P95         float64 `json:"p95"`
+}
+
+// Projection - Monte Carlo projection of portfolio value, same seed gives the same bands
+type Projection struct {
+	Months       int              `json:"months"`
+	Simulations  int              `json:"simulations"`
+	Seed         int64            `json:"seed"`
+	Contribution float64          `json:"contribution"`
+	StartValue   float64          `json:"start_value"`
+	HistoryFrom  string           `json:"history_from"`
+	HistoryTo    string           `json:"history_to"`
+	Bands        []ProjectionBand `json:"bands"`
+}
+
+// ValidateProjection - check horizon, number of simulations and contribution
+func ValidateProjection(months int, simulations int, contribution float64) error {
+	if months < 1 || months > ProjectionMaxMonths {
+		return errors.New("Horizon has to be between 1 and " + strconv.Itoa(ProjectionMaxMonths) + " months.")
+	}
+
+	if simulations < 1 || simulations > ProjectionMaxSimulations {
+		return errors.New("Number of simulations has to be between 1 and " + strconv.Itoa(ProjectionMaxSimulations) + ".")
+	}
+
+	if contribution < 0 {
+		return errors.New("Contribution can not be negative.")
+	}
+
+	return nil
+}
+
+// ProjectPortfolio - simulate portfolio value month by month with daily returns drawn from the last year of price history,
+// or from the day all coins have price history when that is later
+// Every simulated day takes the returns of all coins from one historical day, so coins keep moving together.
+// Monthly contribution is split between coins by their current worth. Coins without price history keep their worth.
+func ProjectPortfolio(DB *sql.DB, coins []Coin, months int, simulations int, contribution float64, seed int64) (Projection, error) {
+	now := dayOf(time.Now())
+
+	projection := Projection{
+		Months:       months,
+		Simulations:  simulations,
+		Seed:         seed,
+		Contribution: contribution,
+		HistoryFrom:  now.AddDate(-1, 0, 0).Format(SnapshotDateFormat),
+		HistoryTo:    now.Format(SnapshotDateFormat),
+		Bands:        []ProjectionBand{},
+	}
+
+	symbols := []string{}
+	worths := []float64{}
+
+	for _, coin := range coins {
+		if i := indexOf(symbols, coin.Symbol); i >= 0 {
+			worths[i] += coin.Worth
+		} else {
+			symbols = append(symbols, coin.Symbol)
+			worths = append(worths, coin.Worth)
+		}
+
+		projection.StartValue += coin.Worth
+	}
+
+	if len(symbols) == 0 {
+		return projection, errors.New("There are no coins in this portfolio yet.")
+	}
+
+	coinIDs := []int{}
+
+	for _, symbol := range symbols {
+		coinIDs = append(coinIDs, GetCoinIDBySymbol(DB, symbol))
+	}
+
+	// History shorter than a year is used from the day every coin has prices
+	historyFrom := clampToHistory(DB, coinIDs, now.AddDate(-1, 0, 0))
+
+	projection.HistoryFrom = historyFrom.Format(SnapshotDateFormat)
+
+	days := []time.Time{}
+
+	for day := historyFrom; !day.After(now); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	if len(days) < 2 {
+		return projection, errors.New("There is not enough price history yet.")
+	}
+
+	returns := [][]float64{}
+	hasHistory := false
+
+	for _, coinID := range coinIDs {
+		prices, ok := dailyCoinPrices(DB, coinID, days)
+
+		if ok {
+			returns = append(returns, dailyReturns(prices))
+			hasHistory = true
+		} else {
+			returns = append(returns, make([]float64, len(days)-1))
+		}
+	}
+
+	if !hasHistory {
+		return projection, errors.New("There is not enough price history yet.")
+	}
+
+	projection.Bands = simulateProjection(worths, returns, now, months, simulations, contribution, seed)
+
+	projection.StartValue = roundNumber(projection.StartValue)
+
+	return projection, nil
+}
+
+// simulateProjection - percentile bands of bootstrapped simulations, returns has the daily returns of every coin
+func simulateProjection(worths []float64, returns [][]float64, now time.Time, months int, simulations int, contribution float64, seed int64) []ProjectionBand {
+	random := rand.New(rand.NewSource(seed))
+
+	start := 0.0
+
+	for _, worth := range worths {
+		start += worth
+	}
+
+	// Contribution goes into coins by their current worth, equally when nothing is held yet
+	weights := make([]float64, len(worths))
+
+	for i, worth := range worths {
+		if start > 0 {
+			weights[i] = worth / start
+		} else {
+			weights[i] = 1 / float64(len(worths))
+		}
+	}
+
+	values := make([][]float64, months+1)
+
+	for month := range values {
+		values[month] = make([]float64, simulations)
+	}
+
+	historyDays := len(returns[0])
+
+	for simulation := 0; simulation < simulations; simulation++ {
+		simulated := append([]float64{}, worths...)
+
+		values[0][simulation] = start
+
+		for month := 1; month <= months; month++ {
+			monthDays := int(now.AddDate(0, month, 0).Sub(now.AddDate(0, month-1, 0)).Hours() / 24)
+
+			for day := 0; day < monthDays && historyDays > 0; day++ {
+				historyDay := random.Intn(historyDays)
+
+				for i := range simulated {
+					simulated[i] *= 1 + returns[i][historyDay]
+				}
+			}
+
+			total := 0.0
+
+			for i := range simulated {
+				simulated[i] += contribution * weights[i]
+				total += simulated[i]
+			}
+
+			values[month][simulation] = total
+		}
+	}
+
+	bands := []ProjectionBand{}
+
+	for month, monthValues := range values {
+		sort.Float64s(monthValues)
+
+		bands = append(bands, ProjectionBand{
+			Date:        now.AddDate(0, month, 0).Format(SnapshotDateFormat),
+			Contributed: roundNumber(contribution * float64(month)),
+			P5:          roundNumber(percentile(monthValues, 5)),
+			P25:         roundNumber(percentile(monthValues, 25)),
+			P50:         roundNumber(percentile(monthValues, 50)),
+			P75:         roundNumber(percentile(monthValues, 75)),
+			P95:         roundNumber(percentile(monthValues, 95)),
+		})
+	}
+
+	return bands
+}
+
+// percentile - value below which the percent of sorted values fall, interpolated between neighbours
+func percentile(sorted []float64, percent float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	position := percent / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
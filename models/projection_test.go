@@ -0,0 +1,121 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimulateProjection(t *testing.T) {
+	now := day(2021, 1, 31)
+
+	returns := [][]float64{
+		{0.05, -0.03, 0.02, -0.04, 0.01, 0.03, -0.02},
+		{0.01, -0.01, 0.04, -0.02, 0.02, -0.03, 0.05},
+	}
+
+	tests := []struct {
+		name         string
+		worths       []float64
+		returns      [][]float64
+		months       int
+		simulations  int
+		contribution float64
+	}{
+		{
+			name:        "one coin",
+			worths:      []float64{1000},
+			returns:     returns[:1],
+			months:      12,
+			simulations: 200,
+		},
+		{
+			name:         "two coins with contribution",
+			worths:       []float64{600, 400},
+			returns:      returns,
+			months:       6,
+			simulations:  100,
+			contribution: 50,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bands := simulateProjection(test.worths, test.returns, now, test.months, test.simulations, test.contribution, 42)
+
+			if again := simulateProjection(test.worths, test.returns, now, test.months, test.simulations, test.contribution, 42); !reflect.DeepEqual(bands, again) {
+				t.Fatalf("simulateProjection() with the same seed = %+v, want %+v", again, bands)
+			}
+
+			if other := simulateProjection(test.worths, test.returns, now, test.months, test.simulations, test.contribution, 43); reflect.DeepEqual(bands, other) {
+				t.Errorf("simulateProjection() with another seed gave the same bands")
+			}
+
+			if len(bands) != test.months+1 {
+				t.Fatalf("simulateProjection() gave %d bands, want %d", len(bands), test.months+1)
+			}
+
+			start := 0.0
+
+			for _, worth := range test.worths {
+				start += worth
+			}
+
+			if first := bands[0]; first.Date != "2021.01.31" || first.Contributed != 0 || first.P5 != start || first.P95 != start {
+				t.Errorf("simulateProjection() first band = %+v, want start value %v", first, start)
+			}
+
+			if bands[1].Date != "2021.03.03" {
+				t.Errorf("simulateProjection() second band date = %v, want 2021.03.03", bands[1].Date)
+			}
+
+			for _, band := range bands {
+				if !(band.P5 <= band.P25 && band.P25 <= band.P50 && band.P50 <= band.P75 && band.P75 <= band.P95) {
+					t.Errorf("simulateProjection() band %+v percentiles are out of order", band)
+				}
+			}
+
+			if last := bands[test.months]; last.Contributed != test.contribution*float64(test.months) {
+				t.Errorf("simulateProjection() contributed = %v, want %v", last.Contributed, test.contribution*float64(test.months))
+			}
+		})
+	}
+}
+
+func TestSimulateProjectionWithoutMoves(t *testing.T) {
+	bands := simulateProjection([]float64{300, 700}, [][]float64{{0, 0, 0}, {0, 0, 0}}, day(2021, 1, 1), 3, 10, 100, 1)
+
+	want := []ProjectionBand{
+		{Date: "2021.01.01", Contributed: 0, P5: 1000, P25: 1000, P50: 1000, P75: 1000, P95: 1000},
+		{Date: "2021.02.01", Contributed: 100, P5: 1100, P25: 1100, P50: 1100, P75: 1100, P95: 1100},
+		{Date: "2021.03.01", Contributed: 200, P5: 1200, P25: 1200, P50: 1200, P75: 1200, P95: 1200},
+		{Date: "2021.04.01", Contributed: 300, P5: 1300, P25: 1300, P50: 1300, P75: 1300, P95: 1300},
+	}
+
+	if !reflect.DeepEqual(bands, want) {
+		t.Errorf("simulateProjection() = %+v, want %+v", bands, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name    string
+		sorted  []float64
+		percent float64
+		want    float64
+	}{
+		{"no values", []float64{}, 50, 0},
+		{"one value", []float64{7}, 95, 7},
+		{"median of odd count", []float64{1, 2, 3}, 50, 2},
+		{"interpolated", []float64{0, 10}, 25, 2.5},
+		{"lowest", []float64{1, 2, 3, 4, 5}, 0, 1},
+		{"highest", []float64{1, 2, 3, 4, 5}, 100, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := percentile(test.sorted, test.percent); got != test.want {
+				t.Errorf("percentile() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
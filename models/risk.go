@@ -0,0 +1,294 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"time"
+)
+
+// TradingDaysPerYear - crypto trades every day, daily figures are annualized with this
+const TradingDaysPerYear = 365
+
+// Drawdown - largest fall from a peak, in percent, with the dates it happened
+// Recovery date is empty while the value is still below the peak.
+type Drawdown struct {
+	MaxDrawdown  float64 `json:"max_drawdown"`
+	PeakDate     string  `json:"peak_date"`
+	TroughDate   string  `json:"trough_date"`
+	RecoveryDate string  `json:"recovery_date"`
+}
+
+// RiskMetrics - risk of the portfolio or a coin, volatility and returns are annualized percentages
+type RiskMetrics struct {
+	Return     float64  `json:"return"`
+	Volatility float64  `json:"volatility"`
+	Sharpe     float64  `json:"sharpe"`
+	Sortino    float64  `json:"sortino"`
+	Drawdown   Drawdown `json:"drawdown"`
+}
+
+// CoinRisk - risk of a held coin, from its price history
+type CoinRisk struct {
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+	RiskMetrics
+}
+
+// Correlation - correlation of daily returns between held coins, Matrix rows and columns follow Symbols
+type Correlation struct {
+	Symbols []string    `json:"symbols"`
+	Matrix  [][]float64 `json:"matrix"`
+}
+
+// Risk - risk metrics of the portfolio and of every held coin
+// Coin metrics cover the days from CoinsFrom, which is later than From when price history starts later.
+type Risk struct {
+	Period       string      `json:"period"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	CoinsFrom    string      `json:"coins_from"`
+	RiskFreeRate float64     `json:"risk_free_rate"`
+	Portfolio    RiskMetrics `json:"portfolio"`
+	Coins        []CoinRisk  `json:"coins"`
+	Correlation  Correlation `json:"correlation"`
+}
+
+// GetRisk - volatility, drawdown, Sharpe and Sortino ratios of the portfolio and its coins, and correlation between the coins
+// Portfolio returns come from snapshots with cash flows taken out, coin returns from price history. Risk-free rate is a yearly percentage.
+func GetRisk(DB *sql.DB, userID int, portfolioID int, period string, riskFreeRate float64) Risk {
+	now := dayOf(time.Now())
+	from := PerformancePeriodStart(period, now)
+
+	points := portfolioValuePoints(GetUserSnapshots(DB, userID, portfolioID), GetUserTransactions(DB, userID, portfolioID))
+
+	base := 0
+
+	for i, point := range points {
+		if point.Date.After(from) {
+			break
+		}
+
+		base = i
+	}
+
+	if len(points) > 0 {
+		points = points[base:]
+	}
+
+	// Coin history starts with the portfolio history, or a year ago when there is none
+	if from.IsZero() {
+		from = now.AddDate(-1, 0, 0)
+
+		if len(points) > 0 {
+			from = points[0].Date
+		}
+	}
+
+	risk := Risk{
+		Period:       period,
+		From:         from.Format(SnapshotDateFormat),
+		To:           now.Format(SnapshotDateFormat),
+		RiskFreeRate: riskFreeRate,
+		Coins:        []CoinRisk{},
+		Correlation:  Correlation{Symbols: []string{}, Matrix: [][]float64{}},
+	}
+
+	portfolioDates := []time.Time{}
+	portfolioReturns := []float64{}
+
+	for i := 1; i < len(points); i++ {
+		invested := points[i-1].Value + points[i].Flow
+
+		if invested <= 0 {
+			continue
+		}
+
+		portfolioDates = append(portfolioDates, points[i].Date)
+		portfolioReturns = append(portfolioReturns, (points[i].Value-invested)/invested)
+	}
+
+	if len(points) > 0 {
+		portfolioDates = append([]time.Time{points[0].Date}, portfolioDates...)
+	}
+
+	risk.Portfolio = riskMetrics(portfolioDates, portfolioReturns, riskFreeRate)
+
+	coins, _ := GetUserCoins(userID, portfolioID, DB)
+
+	coinIDs := []int{}
+
+	for _, coin := range coins {
+		coinIDs = append(coinIDs, GetCoinIDBySymbol(DB, coin.Symbol))
+	}
+
+	// Coin returns start on the first day all coins have price history, so they cover the same days
+	coinsFrom := clampToHistory(DB, coinIDs, from)
+
+	risk.CoinsFrom = coinsFrom.Format(SnapshotDateFormat)
+
+	days := []time.Time{}
+
+	for day := coinsFrom; !day.After(now); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	coinReturns := [][]float64{}
+
+	for i, coin := range coins {
+		if indexOf(risk.Correlation.Symbols, coin.Symbol) >= 0 {
+			continue
+		}
+
+		prices, ok := dailyCoinPrices(DB, coinIDs[i], days)
+
+		if !ok {
+			continue
+		}
+
+		returns := dailyReturns(prices)
+
+		risk.Coins = append(risk.Coins, CoinRisk{Name: coin.Name, Symbol: coin.Symbol, RiskMetrics: riskMetrics(days, returns, riskFreeRate)})
+		risk.Correlation.Symbols = append(risk.Correlation.Symbols, coin.Symbol)
+
+		coinReturns = append(coinReturns, returns)
+	}
+
+	for i := range coinReturns {
+		row := []float64{}
+
+		for j := range coinReturns {
+			row = append(row, roundNumber(correlation(coinReturns[i], coinReturns[j])))
+		}
+
+		risk.Correlation.Matrix = append(risk.Correlation.Matrix, row)
+	}
+
+	return risk
+}
+
+// riskMetrics - metrics of daily returns, dates has the starting day followed by the day of every return
+func riskMetrics(dates []time.Time, returns []float64, riskFreeRate float64) RiskMetrics {
+	metrics := RiskMetrics{}
+
+	if len(returns) < 2 {
+		return metrics
+	}
+
+	dailyRiskFree := riskFreeRate / 100 / TradingDaysPerYear
+
+	mean := 0.0
+
+	for _, value := range returns {
+		mean += value
+	}
+
+	mean = mean / float64(len(returns))
+
+	variance := 0.0
+	downside := 0.0
+
+	for _, value := range returns {
+		variance += (value - mean) * (value - mean)
+
+		if value < dailyRiskFree {
+			downside += (value - dailyRiskFree) * (value - dailyRiskFree)
+		}
+	}
+
+	volatility := math.Sqrt(variance/float64(len(returns)-1)) * math.Sqrt(TradingDaysPerYear)
+	downsideDeviation := math.Sqrt(downside/float64(len(returns))) * math.Sqrt(TradingDaysPerYear)
+
+	excessReturn := (mean - dailyRiskFree) * TradingDaysPerYear
+
+	metrics.Return = roundNumber(mean * TradingDaysPerYear * 100)
+	metrics.Volatility = roundNumber(volatility * 100)
+
+	if volatility > 0 {
+		metrics.Sharpe = roundNumber(excessReturn / volatility)
+	}
+
+	if downsideDeviation > 0 {
+		metrics.Sortino = roundNumber(excessReturn / downsideDeviation)
+	}
+
+	metrics.Drawdown = maxDrawdown(dates, returns)
+
+	return metrics
+}
+
+// maxDrawdown - largest fall of the growth of daily returns from its previous peak
+func maxDrawdown(dates []time.Time, returns []float64) Drawdown {
+	drawdown := Drawdown{}
+
+	growth, peak := 1.0, 1.0
+	peakIndex, maxPeakIndex, troughIndex := 0, 0, -1
+
+	for i, value := range returns {
+		growth *= 1 + value
+
+		if growth >= peak {
+			peak = growth
+			peakIndex = i + 1
+
+			if troughIndex >= 0 && drawdown.RecoveryDate == "" {
+				drawdown.RecoveryDate = dates[i+1].Format(SnapshotDateFormat)
+			}
+
+			continue
+		}
+
+		if fall := (peak - growth) / peak * 100; fall > drawdown.MaxDrawdown {
+			drawdown.MaxDrawdown = fall
+			drawdown.RecoveryDate = ""
+
+			maxPeakIndex = peakIndex
+			troughIndex = i + 1
+		}
+	}
+
+	if troughIndex < 0 {
+		return Drawdown{}
+	}
+
+	drawdown.MaxDrawdown = roundNumber(drawdown.MaxDrawdown)
+	drawdown.PeakDate = dates[maxPeakIndex].Format(SnapshotDateFormat)
+	drawdown.TroughDate = dates[troughIndex].Format(SnapshotDateFormat)
+
+	return drawdown
+}
+
+// correlation - Pearson correlation of two equally long series, 0 when one of them does not move
+func correlation(a []float64, b []float64) float64 {
+	count := len(a)
+
+	if len(b) < count {
+		count = len(b)
+	}
+
+	if count < 2 {
+		return 0
+	}
+
+	meanA, meanB := 0.0, 0.0
+
+	for i := 0; i < count; i++ {
+		meanA += a[i]
+		meanB += b[i]
+	}
+
+	meanA, meanB = meanA/float64(count), meanB/float64(count)
+
+	covariance, varianceA, varianceB := 0.0, 0.0, 0.0
+
+	for i := 0; i < count; i++ {
+		covariance += (a[i] - meanA) * (b[i] - meanB)
+		varianceA += (a[i] - meanA) * (a[i] - meanA)
+		varianceB += (b[i] - meanB) * (b[i] - meanB)
+	}
+
+	if varianceA == 0 || varianceB == 0 {
+		return 0
+	}
+
+	return covariance / math.Sqrt(varianceA*varianceB)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxDrawdown(t *testing.T) {
+	dates := []time.Time{day(2021, 1, 1), day(2021, 1, 2), day(2021, 1, 3), day(2021, 1, 4), day(2021, 1, 5)}
+
+	tests := []struct {
+		name    string
+		returns []float64
+		want    Drawdown
+	}{
+		{
+			name:    "only gains",
+			returns: []float64{0.1, 0.2, 0, 0.1},
+			want:    Drawdown{},
+		},
+		{
+			name:    "fall and recovery",
+			returns: []float64{0.1, -0.5, 0.2, 1},
+			want:    Drawdown{MaxDrawdown: 50, PeakDate: "2021.01.02", TroughDate: "2021.01.03", RecoveryDate: "2021.01.05"},
+		},
+		{
+			name:    "still below the peak",
+			returns: []float64{0.1, -0.5, 0.2, 0.1},
+			want:    Drawdown{MaxDrawdown: 50, PeakDate: "2021.01.02", TroughDate: "2021.01.03"},
+		},
+		{
+			name:    "fall from the starting day",
+			returns: []float64{-0.2, 0.1, -0.25, 0.5},
+			want:    Drawdown{MaxDrawdown: 34, PeakDate: "2021.01.01", TroughDate: "2021.01.04"},
+		},
+		{
+			name:    "larger later fall replaces a recovered one",
+			returns: []float64{-0.1, 0.2, -0.5, 0.1},
+			want:    Drawdown{MaxDrawdown: 50, PeakDate: "2021.01.03", TroughDate: "2021.01.04"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := maxDrawdown(dates, test.returns); got != test.want {
+				t.Errorf("maxDrawdown() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRiskMetrics(t *testing.T) {
+	dates := []time.Time{day(2021, 1, 1), day(2021, 1, 2), day(2021, 1, 3), day(2021, 1, 4), day(2021, 1, 5)}
+
+	tests := []struct {
+		name         string
+		returns      []float64
+		riskFreeRate float64
+		want         RiskMetrics
+	}{
+		{
+			name:    "not enough returns",
+			returns: []float64{0.1},
+			want:    RiskMetrics{},
+		},
+		{
+			name:    "no losses",
+			returns: []float64{0.02, 0, 0.02, 0},
+			want:    RiskMetrics{Return: 365, Volatility: 22.06, Sharpe: 16.55},
+		},
+		{
+			name:    "gains and losses",
+			returns: []float64{0.02, -0.01, 0.02, -0.02},
+			want:    RiskMetrics{Return: 91.25, Volatility: 39.39, Sharpe: 2.32, Sortino: 4.27},
+		},
+		{
+			name:         "risk-free rate lowers the ratios",
+			returns:      []float64{0.02, -0.01, 0.02, -0.02},
+			riskFreeRate: 3.65,
+			want:         RiskMetrics{Return: 91.25, Volatility: 39.39, Sharpe: 2.22, Sortino: 4.08},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := riskMetrics(dates[:len(test.returns)+1], test.returns, test.riskFreeRate)
+
+			// Drawdown has its own test
+			got.Drawdown = Drawdown{}
+
+			if got != test.want {
+				t.Errorf("riskMetrics() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
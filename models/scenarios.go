@@ -0,0 +1,308 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// ScenarioShock - hypothetical move of a coin: a price in EUR, a price in the portfolio currency, a change in percent,
+// or a beta against the reference coin. Prices in the portfolio currency are turned into EUR by ScenarioPricesToEUR.
+type ScenarioShock struct {
+	Symbol   string   `json:"symbol"`
+	PriceEur *float64 `json:"priceeur"`
+	Price    *float64 `json:"price"`
+	Change   *float64 `json:"change"`
+	Beta     *float64 `json:"beta"`
+}
+
+// Scenario - shocks applied to the holdings, nothing is saved
+// Coins without a shock of their own follow the reference coin with beta estimated from price history when asked for, otherwise with the default beta.
+type Scenario struct {
+	Reference    string          `json:"reference"`
+	DefaultBeta  *float64        `json:"default_beta"`
+	EstimateBeta bool            `json:"estimate_beta"`
+	Shocks       []ScenarioShock `json:"shocks"`
+}
+
+// ScenarioChange - price change applied to a coin, in percent, with the beta it followed the reference coin with
+type ScenarioChange struct {
+	Symbol string   `json:"symbol"`
+	Change float64  `json:"change"`
+	Beta   *float64 `json:"beta"`
+}
+
+// ScenarioResult - holdings and totals after the shocks, compared with the current ones
+type ScenarioResult struct {
+	CoinData           []Coin           `json:"coindata"`
+	SyncData           SyncInfo         `json:"syncdata"`
+	Current            SyncInfo         `json:"current"`
+	WorthChange        float64          `json:"worth_change"`
+	WorthChangePercent float64          `json:"worth_change_percent"`
+	Changes            []ScenarioChange `json:"changes"`
+}
+
+// ValidateScenario - check shocked coins exist and every shock can be applied
+func ValidateScenario(DB *sql.DB, scenario Scenario) error {
+	symbols := []string{}
+	usesBeta := scenario.DefaultBeta != nil || scenario.EstimateBeta
+	referenceShocked := false
+
+	for _, shock := range scenario.Shocks {
+		if name, _ := GetCoinBySymbol(DB, shock.Symbol); name == "" {
+			return errors.New("Coin " + shock.Symbol + " does not exist!")
+		}
+
+		if indexOf(symbols, shock.Symbol) >= 0 {
+			return errors.New("Coin " + shock.Symbol + " has more than one shock.")
+		}
+
+		given := 0
+
+		for _, value := range []*float64{shock.PriceEur, shock.Price, shock.Change, shock.Beta} {
+			if value != nil {
+				given++
+			}
+		}
+
+		if given != 1 {
+			return errors.New("Every shock needs exactly one of price, change or beta.")
+		}
+
+		if (shock.PriceEur != nil && *shock.PriceEur < 0) || (shock.Price != nil && *shock.Price < 0) {
+			return errors.New("Prices can not be negative.")
+		}
+
+		if shock.Change != nil && *shock.Change < -100 {
+			return errors.New("Prices can not fall more than 100%.")
+		}
+
+		if shock.Beta != nil {
+			usesBeta = true
+		}
+
+		if shock.Symbol == scenario.Reference && shock.Beta == nil {
+			referenceShocked = true
+		}
+
+		symbols = append(symbols, shock.Symbol)
+	}
+
+	if usesBeta && !referenceShocked {
+		return errors.New("Please provide a price or change for the reference coin.")
+	}
+
+	return nil
+}
+
+// ScenarioPricesToEUR - turn shock prices given in the currency into EUR prices
+// Prices stay as given when the rate is not known yet, like values converted by ConvertCoins.
+func ScenarioPricesToEUR(DB *sql.DB, scenario Scenario, currency string) Scenario {
+	rate, ok := GetFXRate(DB, currency)
+
+	if !ok {
+		rate = 1
+	}
+
+	shocks := make([]ScenarioShock, len(scenario.Shocks))
+
+	for i, shock := range scenario.Shocks {
+		if shock.Price != nil {
+			priceEur := *shock.Price / rate
+
+			shock.PriceEur = &priceEur
+			shock.Price = nil
+		}
+
+		shocks[i] = shock
+	}
+
+	scenario.Shocks = shocks
+
+	return scenario
+}
+
+// ConvertScenarioResult - convert EUR values of the result into the currency, worth change comes from the converted totals
+func ConvertScenarioResult(DB *sql.DB, result ScenarioResult, currency string) (ScenarioResult, string) {
+	result.CoinData, result.SyncData, currency = ConvertCoins(DB, result.CoinData, result.SyncData, currency)
+	_, result.Current, _ = ConvertCoins(DB, []Coin{}, result.Current, currency)
+
+	result.WorthChange = roundNumber(result.SyncData.Worth - result.Current.Worth)
+
+	return result, currency
+}
+
+// RunScenario - recalculate holdings and totals with the shocked prices
+func RunScenario(DB *sql.DB, coins []Coin, scenario Scenario) ScenarioResult {
+	shocks := map[string]ScenarioShock{}
+
+	for _, shock := range scenario.Shocks {
+		shocks[shock.Symbol] = shock
+	}
+
+	referenceChange := 0.0
+
+	if reference, ok := shocks[scenario.Reference]; ok {
+		_, price := GetCoinBySymbol(DB, reference.Symbol)
+
+		referenceChange = scenarioChange(reference, price, 0)
+	}
+
+	result := ScenarioResult{CoinData: []Coin{}, Current: CalculateSyncInfo(coins), Changes: []ScenarioChange{}}
+
+	changes := map[string]ScenarioChange{}
+
+	for _, coin := range coins {
+		change, ok := changes[coin.Symbol]
+
+		if !ok {
+			change = ScenarioChange{Symbol: coin.Symbol}
+
+			shock, shocked := shocks[coin.Symbol]
+
+			if !shocked && coin.Symbol != scenario.Reference {
+				beta := scenario.DefaultBeta
+
+				if scenario.EstimateBeta {
+					if estimated, ok := estimateBeta(DB, coin.Symbol, scenario.Reference); ok {
+						beta = &estimated
+					}
+				}
+
+				shock = ScenarioShock{Symbol: coin.Symbol, Beta: beta}
+			}
+
+			change.Beta = shock.Beta
+			change.Change = roundNumber(scenarioChange(shock, coin.PriceEur, referenceChange))
+
+			changes[coin.Symbol] = change
+			result.Changes = append(result.Changes, change)
+		}
+
+		price := coin.PriceEur * (1 + change.Change/100)
+
+		// Hypothetical prices are used as given, not through the rounded change
+		if shock, ok := shocks[coin.Symbol]; ok && shock.PriceEur != nil {
+			price = *shock.PriceEur
+		}
+
+		coin.PriceEur = price
+		coin.Worth = roundNumber(price * coin.Amount)
+		coin.MadeLost = roundNumber(coin.Worth - coin.Invested)
+		coin.TakeProfit = targetProgress(coin.TakeProfit, price, coin.BreakEven, true)
+		coin.StopLoss = targetProgress(coin.StopLoss, price, coin.BreakEven, false)
+
+		result.CoinData = append(result.CoinData, coin)
+	}
+
+	result.SyncData = CalculateSyncInfo(result.CoinData)
+	result.WorthChange = roundNumber(result.SyncData.Worth - result.Current.Worth)
+
+	if result.Current.Worth > 0 {
+		result.WorthChangePercent = roundNumber(result.WorthChange / result.Current.Worth * 100)
+	}
+
+	return result
+}
+
+// scenarioChange - price change of the shock in percent, beta shocks never fall below -100
+func scenarioChange(shock ScenarioShock, price float64, referenceChange float64) float64 {
+	switch {
+	case shock.PriceEur != nil:
+		if price <= 0 {
+			return 0
+		}
+
+		return (*shock.PriceEur/price - 1) * 100
+	case shock.Change != nil:
+		return *shock.Change
+	case shock.Beta != nil:
+		return math.Max(*shock.Beta*referenceChange, -100)
+	}
+
+	return 0
+}
+
+// estimateBeta - beta of the coin's daily returns against the reference coin over the last year, or since both have
+// price history when that is shorter, false without price history
+func estimateBeta(DB *sql.DB, symbol string, reference string) (float64, bool) {
+	now := dayOf(time.Now())
+
+	coinID := GetCoinIDBySymbol(DB, symbol)
+	referenceID := GetCoinIDBySymbol(DB, reference)
+
+	days := []time.Time{}
+
+	for day := clampToHistory(DB, []int{coinID, referenceID}, now.AddDate(-1, 0, 0)); !day.After(now); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	coinPrices, ok := dailyCoinPrices(DB, coinID, days)
+
+	if !ok {
+		return 0, false
+	}
+
+	referencePrices, ok := dailyCoinPrices(DB, referenceID, days)
+
+	if !ok {
+		return 0, false
+	}
+
+	beta, ok := betaOf(dailyReturns(coinPrices), dailyReturns(referencePrices))
+
+	return roundNumber(beta), ok
+}
+
+// dailyReturns - change of each price from the one before it, 0 after a missing price
+func dailyReturns(prices []float64) []float64 {
+	returns := []float64{}
+
+	for i := 1; i < len(prices); i++ {
+		if prices[i-1] > 0 {
+			returns = append(returns, prices[i]/prices[i-1]-1)
+		} else {
+			returns = append(returns, 0)
+		}
+	}
+
+	return returns
+}
+
+// betaOf - covariance of the returns with the reference returns divided by the reference variance, false when the reference does not move
+func betaOf(returns []float64, referenceReturns []float64) (float64, bool) {
+	count := len(returns)
+
+	if len(referenceReturns) < count {
+		count = len(referenceReturns)
+	}
+
+	if count < 2 {
+		return 0, false
+	}
+
+	mean, referenceMean := 0.0, 0.0
+
+	for i := 0; i < count; i++ {
+		mean += returns[i]
+		referenceMean += referenceReturns[i]
+	}
+
+	mean, referenceMean = mean/float64(count), referenceMean/float64(count)
+
+	covariance, variance := 0.0, 0.0
+
+	for i := 0; i < count; i++ {
+		covariance += (returns[i] - mean) * (referenceReturns[i] - referenceMean)
+		variance += (referenceReturns[i] - referenceMean) * (referenceReturns[i] - referenceMean)
+	}
+
+	if variance == 0 {
+		return 0, false
+	}
+
+	return covariance / variance, true
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// SnapshotDateFormat - one snapshot is kept per user per day
+const SnapshotDateFormat = "2006.01.02"
+
+// Snapshot - user's portfolio totals at the end of the day
+type Snapshot struct {
+	SnapshotID   int     `json:"snapshotid"`
+	Invested     float64 `json:"invested"`
+	Worth        float64 `json:"worth"`
+	Profit       float64 `json:"profit"`
+	DateSnapshot string  `json:"date_snapshot"`
+	DateUpdated  string  `json:"date_updated"`
+}
+
+// RecordSnapshot - store today's totals of the portfolio, later syncs on the same day overwrite them
+func RecordSnapshot(DB *sql.DB, userID int, portfolioID int, syncInfo SyncInfo) int {
+	today := time.Now().Format(SnapshotDateFormat)
+
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE snapshots SET invested = $1, worth = $2, profit = $3, date_updated = $4 WHERE userid = $5 AND portfolioid = $6 AND date_snapshot = $7 returning snapshotid;",
+		syncInfo.Invested, syncInfo.Worth, syncInfo.Profit, helpers.GetCurrentDateTime(), userID, portfolioID, today).Scan(&lastUpdatedID)
+
+	if err == nil {
+		return lastUpdatedID
+	}
+
+	if err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	lastInsertID := 0
+
+	err = DB.QueryRow("INSERT INTO snapshots(userid, portfolioid, invested, worth, profit, date_snapshot, date_updated) VALUES($1, $2, $3, $4, $5, $6, $7) returning snapshotid;",
+		userID, portfolioID, syncInfo.Invested, syncInfo.Worth, syncInfo.Profit, today, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// GetUserSnapshot - snapshot of user's portfolio for the date, SnapshotID is 0 if there is none
+func GetUserSnapshot(DB *sql.DB, userID int, portfolioID int, date string) Snapshot {
+	var snapshot Snapshot
+
+	err := DB.QueryRow("SELECT snapshotid, invested, worth, profit, date_snapshot, date_updated FROM snapshots WHERE userid = $1 AND portfolioid = $2 AND date_snapshot = $3", userID, portfolioID, date).Scan(
+		&snapshot.SnapshotID, &snapshot.Invested, &snapshot.Worth, &snapshot.Profit, &snapshot.DateSnapshot, &snapshot.DateUpdated)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return snapshot
+}
+
+// GetUserSnapshots - get all snapshots of user's portfolio, oldest first
+func GetUserSnapshots(DB *sql.DB, userID int, portfolioID int) []Snapshot {
+	rows, err := DB.Query("SELECT snapshotid, invested, worth, profit, date_snapshot, date_updated FROM snapshots WHERE userid = $1 AND portfolioid = $2 ORDER BY date_snapshot", userID, portfolioID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var snapshots []Snapshot
+
+	// Foreach snapshot
+	for rows.Next() {
+		var snapshot Snapshot
+
+		err = rows.Scan(&snapshot.SnapshotID, &snapshot.Invested, &snapshot.Worth, &snapshot.Profit, &snapshot.DateSnapshot, &snapshot.DateUpdated)
+
+		if err != nil {
+			panic(err)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots
+}
+
+// GetUserSnapshotBefore - latest snapshot of user's portfolio on or before the date, SnapshotID is 0 if there is none
+func GetUserSnapshotBefore(DB *sql.DB, userID int, portfolioID int, date string) Snapshot {
+	var snapshot Snapshot
+
+	err := DB.QueryRow("SELECT snapshotid, invested, worth, profit, date_snapshot, date_updated FROM snapshots WHERE userid = $1 AND portfolioid = $2 AND date_snapshot <= $3 ORDER BY date_snapshot DESC LIMIT 1", userID, portfolioID, date).Scan(
+		&snapshot.SnapshotID, &snapshot.Invested, &snapshot.Worth, &snapshot.Profit, &snapshot.DateSnapshot, &snapshot.DateUpdated)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return snapshot
+}
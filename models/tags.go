@@ -0,0 +1,221 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// UntaggedName - group of holdings without tags
+const UntaggedName = "Untagged"
+
+// CoinGroup - coins sharing a tag and their subtotals
+type CoinGroup struct {
+	Name     string   `json:"name"`
+	CoinData []Coin   `json:"coindata"`
+	SyncData SyncInfo `json:"syncdata"`
+}
+
+// NormalizeTags - trimmed, unique tag names in the order they were given
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "" || len(tag) > 50 {
+			return nil, errors.New("Tags have to be between 1 and 50 characters.")
+		}
+
+		if indexOf(normalized, tag) < 0 {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, nil
+}
+
+// GetUserTags - names of all tags the user has created
+func GetUserTags(DB *sql.DB, userID int) []string {
+	rows, err := DB.Query("SELECT name FROM tags WHERE userid = $1 ORDER BY name", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	tags := []string{}
+
+	// Foreach tag
+	for rows.Next() {
+		var tag string
+
+		if err = rows.Scan(&tag); err != nil {
+			panic(err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// GetHoldingTags - tag names of user's holdings by holding ID
+func GetHoldingTags(DB *sql.DB, userID int) map[int][]string {
+	rows, err := DB.Query("SELECT h.usercoinid, t.name FROM holdingtags h JOIN tags t ON t.tagid = h.tagid WHERE t.userid = $1 ORDER BY t.name", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	holdingTags := map[int][]string{}
+
+	// Foreach holding tag
+	for rows.Next() {
+		var userCoinID int
+		var tag string
+
+		if err = rows.Scan(&userCoinID, &tag); err != nil {
+			panic(err)
+		}
+
+		holdingTags[userCoinID] = append(holdingTags[userCoinID], tag)
+	}
+
+	return holdingTags
+}
+
+// GetTransactionTags - tag names of user's transactions by transaction ID
+func GetTransactionTags(DB *sql.DB, userID int) map[int][]string {
+	rows, err := DB.Query("SELECT x.transactionid, t.name FROM transactiontags x JOIN tags t ON t.tagid = x.tagid WHERE t.userid = $1 ORDER BY t.name", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	transactionTags := map[int][]string{}
+
+	// Foreach transaction tag
+	for rows.Next() {
+		var transactionID int
+		var tag string
+
+		if err = rows.Scan(&transactionID, &tag); err != nil {
+			panic(err)
+		}
+
+		transactionTags[transactionID] = append(transactionTags[transactionID], tag)
+	}
+
+	return transactionTags
+}
+
+// SetTransactionTags - replace tags of the transaction, tags that do not exist yet are created
+func SetTransactionTags(DB queryer, userID int, transactionID int, tags []string) {
+	_, err := DB.Exec("DELETE FROM transactiontags WHERE transactionid = $1", transactionID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, tag := range tags {
+		_, err = DB.Exec("INSERT INTO transactiontags(transactionid, tagid) VALUES($1, $2)", transactionID, getOrCreateTag(DB, userID, tag))
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// FilterCoinsByTag - coins that have the tag, UntaggedName gives coins without tags
+func FilterCoinsByTag(coins []Coin, tag string) []Coin {
+	filtered := []Coin{}
+
+	for _, coin := range coins {
+		if indexOf(coin.Tags, tag) >= 0 || (tag == UntaggedName && len(coin.Tags) == 0) {
+			filtered = append(filtered, coin)
+		}
+	}
+
+	return filtered
+}
+
+// GroupCoinsByTag - coins grouped by tag with subtotals, sorted by tag name with untagged coins last
+// Coins with several tags are in each of their groups, so subtotals can add up to more than the total.
+func GroupCoinsByTag(coins []Coin) []CoinGroup {
+	names := []string{}
+
+	for _, coin := range coins {
+		for _, tag := range coin.Tags {
+			if indexOf(names, tag) < 0 {
+				names = append(names, tag)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	names = append(names, UntaggedName)
+
+	groups := []CoinGroup{}
+
+	for _, name := range names {
+		groupCoins := FilterCoinsByTag(coins, name)
+
+		if len(groupCoins) == 0 {
+			continue
+		}
+
+		groups = append(groups, CoinGroup{Name: name, CoinData: groupCoins, SyncData: CalculateSyncInfo(groupCoins)})
+	}
+
+	return groups
+}
+
+// SetHoldingTags - replace tags of the holding, tags that do not exist yet are created
+func SetHoldingTags(DB queryer, userID int, userCoinID int, tags []string) {
+	_, err := DB.Exec("DELETE FROM holdingtags WHERE usercoinid = $1", userCoinID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	for _, tag := range tags {
+		_, err = DB.Exec("INSERT INTO holdingtags(usercoinid, tagid) VALUES($1, $2)", userCoinID, getOrCreateTag(DB, userID, tag))
+
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// getOrCreateTag - ID of user's tag with the name, created when it does not exist
+func getOrCreateTag(DB queryer, userID int, name string) int {
+	tagID := 0
+
+	err := DB.QueryRow("SELECT tagid FROM tags WHERE userid = $1 AND name = $2", userID, name).Scan(&tagID)
+
+	if err == nil {
+		return tagID
+	}
+
+	if err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	err = DB.QueryRow("INSERT INTO tags(userid, name) VALUES($1, $2) returning tagid;", userID, name).Scan(&tagID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return tagID
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+
+	_ "github.com/lib/pq"
+)
+
+// Target - take-profit or stop-loss price of a holding and how close the current price is to it
+// Distance is the price change in percent still needed to reach the target, progress is how far
+// the price has moved from the break-even price towards the target, in percent.
+type Target struct {
+	Price    float64 `json:"price"`
+	Distance float64 `json:"distance"`
+	Progress float64 `json:"progress"`
+	Reached  bool    `json:"reached"`
+}
+
+// ValidateHoldingTargets - check target prices, 0 removes a target
+func ValidateHoldingTargets(takeProfit float64, stopLoss float64) error {
+	if takeProfit < 0 || stopLoss < 0 {
+		return errors.New("Target prices can not be negative.")
+	}
+
+	if takeProfit > 0 && stopLoss > 0 && stopLoss >= takeProfit {
+		return errors.New("Stop-loss price has to be lower than take-profit price.")
+	}
+
+	return nil
+}
+
+// SetHoldingTargets - set take-profit and stop-loss prices of the holding, in EUR
+func SetHoldingTargets(DB *sql.DB, coinid string, takeProfit float64, stopLoss float64) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE usercoins SET take_profit = $1, stop_loss = $2 WHERE usercoinid = $3 returning usercoinid;", takeProfit, stopLoss, coinid).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// UpdateTargetAlerts - keep price alerts of the holding in line with its targets, returns number of alerts added or changed
+// Alerts of removed targets are deleted and alerts of changed targets are moved to the new price and armed again.
+// With create set, targets the user has no alert for yet get one that fires once.
+func UpdateTargetAlerts(DB *sql.DB, userID int, coinid string, takeProfit float64, stopLoss float64, create bool) int {
+	var userCoinID int
+	var symbol string
+
+	err := DB.QueryRow("SELECT usercoinid, symbol FROM usercoins WHERE usercoinid = $1", coinid).Scan(&userCoinID, &symbol)
+
+	if err == sql.ErrNoRows {
+		return 0
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	changed := 0
+
+	for _, alert := range []Alert{
+		{UserCoinID: userCoinID, Symbol: symbol, Condition: "price_above", Threshold: takeProfit},
+		{UserCoinID: userCoinID, Symbol: symbol, Condition: "price_below", Threshold: stopLoss},
+	} {
+		if alert.Threshold <= 0 {
+			removeTargetAlerts(DB, userCoinID, alert.Condition)
+
+			continue
+		}
+
+		result, err := DB.Exec("UPDATE alerts SET threshold = $1, armed = true, enabled = true WHERE usercoinid = $2 AND condition = $3 AND threshold <> $1", alert.Threshold, userCoinID, alert.Condition)
+
+		if err != nil {
+			panic(err)
+		}
+
+		updated, _ := result.RowsAffected()
+
+		changed += int(updated)
+
+		if !create || countTargetAlerts(DB, userID, userCoinID, alert.Condition) > 0 {
+			continue
+		}
+
+		alert.CoinID = GetCoinIDBySymbol(DB, symbol)
+		alert.Enabled = true
+
+		if CreateAlert(DB, userID, alert) > 0 {
+			changed++
+		}
+	}
+
+	return changed
+}
+
+// removeTargetAlerts - delete holding's target alerts with the condition and their firings
+func removeTargetAlerts(DB *sql.DB, userCoinID int, condition string) {
+	_, err := DB.Exec("DELETE FROM alertfirings WHERE alertid IN (SELECT alertid FROM alerts WHERE usercoinid = $1 AND condition = $2)", userCoinID, condition)
+
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = DB.Exec("DELETE FROM alerts WHERE usercoinid = $1 AND condition = $2", userCoinID, condition)
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// countTargetAlerts - count user's alerts on the holding's target with the condition
+func countTargetAlerts(DB *sql.DB, userID int, userCoinID int, condition string) int {
+	count := 0
+
+	err := DB.QueryRow("SELECT COUNT(*) FROM alerts WHERE userid = $1 AND usercoinid = $2 AND condition = $3", userID, userCoinID, condition).Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count
+}
+
+// setUnitFigures - average buy price, break-even price and target progress of the holding at the price
+// Invested cost includes buy fees, so it gives the break-even price; the average buy price leaves out the
+// share of fees paid on buys in the ledger.
+func setUnitFigures(DB *sql.DB, coin *Coin, priceEur float64) {
+	if coin.Amount <= 0 {
+		return
+	}
+
+	coin.BreakEven = coin.Invested / coin.Amount
+	coin.AvgBuyPrice = coin.BreakEven
+
+	var fees, costs float64
+
+	err := DB.QueryRow("SELECT COALESCE(SUM(fee), 0), COALESCE(SUM(total + fee), 0) FROM transactions WHERE usercoinid = $1 AND type = $2", coin.UserCoinID, "buy").Scan(&fees, &costs)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if costs > 0 {
+		coin.AvgBuyPrice = coin.BreakEven * (1 - fees/costs)
+	}
+
+	coin.TakeProfit = targetProgress(coin.TakeProfit, priceEur, coin.BreakEven, true)
+	coin.StopLoss = targetProgress(coin.StopLoss, priceEur, coin.BreakEven, false)
+}
+
+// targetProgress - target with distance and progress at the price, nil when no target is set
+func targetProgress(target *Target, priceEur float64, breakEven float64, above bool) *Target {
+	if target == nil {
+		return nil
+	}
+
+	progress := Target{Price: target.Price}
+
+	if priceEur > 0 {
+		progress.Distance = roundNumber((target.Price/priceEur - 1) * 100)
+	}
+
+	if target.Price != breakEven {
+		progress.Progress = roundNumber((priceEur - breakEven) / (target.Price - breakEven) * 100)
+	}
+
+	if above {
+		progress.Reached = priceEur >= target.Price
+	} else {
+		progress.Reached = priceEur <= target.Price
+	}
+
+	return &progress
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// oversellTolerance - share of the holding a sell can go over it by, for amounts rounded when stored
+const oversellTolerance = 1e-6
+
+// Transaction - single buy or sell in user's ledger
+type Transaction struct {
+	TransactionID   int      `json:"transactionid"`
+	UserCoinID      int      `json:"coinid"`
+	PortfolioID     int      `json:"portfolioid"`
+	Type            string   `json:"type"`
+	Name            string   `json:"name"`
+	Symbol          string   `json:"symbol"`
+	Amount          float64  `json:"amount"`
+	PriceEur        float64  `json:"priceeur"`
+	Fee             float64  `json:"fee"`
+	Total           float64  `json:"total"`
+	Lives           string   `json:"lives"`
+	Source          string   `json:"source"`
+	Hash            string   `json:"hash"`
+	Notes           string   `json:"notes"`
+	Tags            []string `json:"tags"`
+	DateTransaction string   `json:"date_transaction"`
+	DateAdded       string   `json:"date_added"`
+}
+
+// queryer - database or an open DB transaction, so ledger writes can be applied together
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// CreateTransaction - insert transaction into user's ledger
+func CreateTransaction(DB queryer, userID int, transaction Transaction) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO transactions(userid, portfolioid, usercoinid, type, name, symbol, amount, priceeur, fee, total, lives, source, hash, notes, date_transaction, date_added) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) returning transactionid;",
+		userID, transaction.PortfolioID, transaction.UserCoinID, transaction.Type, transaction.Name, transaction.Symbol, transaction.Amount, transaction.PriceEur, transaction.Fee,
+		transaction.Total, transaction.Lives, transaction.Source, transaction.Hash, transaction.Notes, transaction.DateTransaction, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if len(transaction.Tags) > 0 {
+		SetTransactionTags(DB, userID, lastInsertID, transaction.Tags)
+	}
+
+	return lastInsertID
+}
+
+// CheckTransactionHashExists - see if transaction with this hash was imported already
+func CheckTransactionHashExists(DB queryer, userID int, hash string) int {
+	count := 0
+
+	row := DB.QueryRow("SELECT COUNT(*) FROM transactions where userid = $1 AND hash = $2", userID, hash)
+
+	err := row.Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count
+}
+
+// GetUserTransactions - get all transactions of user's portfolio, oldest first
+// AllPortfolios returns transactions of every portfolio.
+func GetUserTransactions(DB *sql.DB, userID int, portfolioID int) []Transaction {
+	rows, err := DB.Query("SELECT transactionid, portfolioid, usercoinid, type, name, symbol, amount, priceeur, fee, total, lives, source, hash, notes, date_transaction, date_added FROM transactions WHERE userid = $1 AND ($2 = 0 OR portfolioid = $2) ORDER BY date_transaction, transactionid", userID, portfolioID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var transactions []Transaction
+
+	transactionTags := GetTransactionTags(DB, userID)
+
+	// Foreach transaction
+	for rows.Next() {
+		var transaction Transaction
+
+		err = rows.Scan(&transaction.TransactionID, &transaction.PortfolioID, &transaction.UserCoinID, &transaction.Type, &transaction.Name, &transaction.Symbol, &transaction.Amount,
+			&transaction.PriceEur, &transaction.Fee, &transaction.Total, &transaction.Lives, &transaction.Source, &transaction.Hash,
+			&transaction.Notes, &transaction.DateTransaction, &transaction.DateAdded)
+
+		if err != nil {
+			panic(err)
+		}
+
+		transaction.Tags = transactionTags[transaction.TransactionID]
+
+		if transaction.Tags == nil {
+			transaction.Tags = []string{}
+		}
+
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions
+}
+
+// ApplyTransaction - update (or create) holding in transaction's portfolio and store the transaction in the ledger
+// Both are written in one DB transaction, so the holding never gets out of sync with the ledger.
+// Returns 0 if the transaction could not be applied.
+func ApplyTransaction(DB *sql.DB, userID int, transaction Transaction) int {
+	tx, err := DB.Begin()
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer tx.Rollback()
+
+	transactionID := applyTransaction(tx, userID, transaction)
+
+	if transactionID < 1 {
+		return 0
+	}
+
+	if err = tx.Commit(); err != nil {
+		panic(err)
+	}
+
+	return transactionID
+}
+
+// applyTransaction - apply transaction to the holding and the ledger through DB, which can be an open DB transaction
+// Buys add the amount and cost (including fee) to the holding, sells remove the amount and
+// the matching part of the invested cost. Sells of more than the holding has are refused, returning 0.
+func applyTransaction(DB queryer, userID int, transaction Transaction) int {
+	var userCoinID int
+	var invested float64
+	var amount float64
+
+	row := DB.QueryRow("SELECT usercoinid, invested, amount FROM usercoins WHERE userid = $1 AND portfolioid = $2 AND name = $3 AND symbol = $4",
+		userID, transaction.PortfolioID, transaction.Name, transaction.Symbol)
+
+	err := row.Scan(&userCoinID, &invested, &amount)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	if transaction.Type == "sell" {
+		if userCoinID < 1 || amount <= 0 {
+			return 0
+		}
+
+		// Holding amounts are stored as real, selling all of it can be a little over the stored amount
+		if transaction.Amount > amount*(1+oversellTolerance) {
+			return 0
+		}
+
+		soldAmount := math.Min(transaction.Amount, amount)
+
+		invested = invested - invested*soldAmount/amount
+		amount = amount - soldAmount
+
+		if UpdateHoldingTotals(DB, userCoinID, invested, amount) < 1 {
+			return 0
+		}
+	} else if userCoinID < 1 {
+		userCoinID = CreateCoin(DB, userID, transaction.PortfolioID, transaction.Name, transaction.Symbol, transaction.Total+transaction.Fee, transaction.Amount, transaction.PriceEur, transaction.Lives)
+
+		if userCoinID < 1 {
+			return 0
+		}
+	} else {
+		if UpdateHoldingTotals(DB, userCoinID, invested+transaction.Total+transaction.Fee, amount+transaction.Amount) < 1 {
+			return 0
+		}
+	}
+
+	transaction.UserCoinID = userCoinID
+
+	return CreateTransaction(DB, userID, transaction)
+}
+
+// UpdateHoldingTotals - set invested and amount of the holding
+func UpdateHoldingTotals(DB queryer, userCoinID int, invested float64, amount float64) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE usercoins SET invested = $1, amount = $2, date_updated = $3 WHERE usercoinid = $4 returning usercoinid;",
+		invested, amount, helpers.GetCurrentDateTime(), userCoinID).Scan(&lastUpdatedID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// NewTransactionHash - unique hash for transactions entered by hand, imported ones are hashed from the CSV row
+func NewTransactionHash(transaction Transaction) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%g|%g|%d", transaction.Source, transaction.DateTransaction, transaction.Symbol,
+		transaction.Amount, transaction.Total, time.Now().UnixNano())))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// GetTransactionPortfolio - owner and portfolio of the transaction, both 0 if it does not exist
+func GetTransactionPortfolio(DB *sql.DB, transactionID int) (int, int) {
+	var ownerID, portfolioID int
+
+	err := DB.QueryRow("SELECT userid, portfolioid FROM transactions WHERE transactionid = $1", transactionID).Scan(&ownerID, &portfolioID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return ownerID, portfolioID
+}
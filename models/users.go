@@ -392,3 +392,90 @@ func GetUserProfile(DB *sql.DB, userID int) UserProfile {
 
 	return userProfile
 }
+
+// UserSetting - name and value of a setting from usersettings table
+type UserSetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// GetUserSettings - get all settings of the user
+func GetUserSettings(DB *sql.DB, userID int) []UserSetting {
+	rows, err := DB.Query("SELECT name, value FROM usersettings WHERE userid = $1 ORDER BY usersettingid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var settings []UserSetting
+
+	// Foreach usersetting
+	for rows.Next() {
+		var setting UserSetting
+
+		err = rows.Scan(&setting.Name, &setting.Value)
+
+		if err != nil {
+			panic(err)
+		}
+
+		settings = append(settings, setting)
+	}
+
+	return settings
+}
+
+// GetUserSetting - get value of a single setting, empty if it is not set
+func GetUserSetting(DB queryer, userID int, name string) string {
+	var value string
+
+	err := DB.QueryRow("SELECT value FROM usersettings WHERE userid = $1 AND name = $2", userID, name).Scan(&value)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return value
+}
+
+// SetUserSetting - create or update a setting
+func SetUserSetting(DB queryer, userID int, name string, value string) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE usersettings SET value = $1 WHERE userid = $2 AND name = $3 returning usersettingid;", value, userID, name).Scan(&lastUpdatedID)
+
+	if err == nil {
+		return lastUpdatedID
+	}
+
+	if err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	lastInsertID := 0
+
+	err = DB.QueryRow("INSERT INTO usersettings(userid, name, value) VALUES($1, $2, $3) returning usersettingid;", userID, name, value).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// CountProfitsEndpointUsers - count other users that use this profits endpoint
+func CountProfitsEndpointUsers(DB queryer, userID int, endpoint string) int {
+	count := 0
+
+	row := DB.QueryRow("SELECT COUNT(*) FROM usersettings WHERE name = $1 AND value = $2 AND userid != $3", "profits_endpoint", endpoint, userID)
+
+	err := row.Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// WatchlistEntry - coin the user follows without holding it, price info comes from coins table
+type WatchlistEntry struct {
+	WatchlistID      int     `json:"watchlistid"`
+	CoinID           int     `json:"coinid"`
+	Name             string  `json:"name"`
+	Symbol           string  `json:"symbol"`
+	PriceEur         float64 `json:"priceeur"`
+	PercentChange24h float64 `json:"percent_change_24h"`
+	Held             bool    `json:"held"`
+	Notes            string  `json:"notes"`
+	DateAdded        string  `json:"date_added"`
+}
+
+// AddToWatchlist - add coin to user's watchlist
+func AddToWatchlist(DB queryer, userID int, coinID int, notes string) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO watchlist(userid, coinid, notes, date_added) VALUES($1, $2, $3, $4) returning watchlistid;",
+		userID, coinID, notes, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// UpdateWatchlistNotes - update notes of watchlist entry
+func UpdateWatchlistNotes(DB *sql.DB, userID int, watchlistID int, notes string) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE watchlist SET notes = $1 WHERE watchlistid = $2 AND userid = $3 returning watchlistid;", notes, watchlistID, userID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// RemoveFromWatchlist - delete watchlist entry
+func RemoveFromWatchlist(DB *sql.DB, userID int, watchlistID int) bool {
+	result, err := DB.Exec("DELETE FROM watchlist WHERE watchlistid = $1 AND userid = $2", watchlistID, userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed > 0
+}
+
+// IsCoinWatched - check if the coin is already on user's watchlist
+func IsCoinWatched(DB queryer, userID int, coinID int) bool {
+	count := 0
+
+	err := DB.QueryRow("SELECT COUNT(*) FROM watchlist WHERE userid = $1 AND coinid = $2", userID, coinID).Scan(&count)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return count > 0
+}
+
+// GetUserWatchlist - user's watchlist with latest prices, in the order coins were added
+func GetUserWatchlist(DB *sql.DB, userID int) []WatchlistEntry {
+	rows, err := DB.Query("SELECT w.watchlistid, w.coinid, c.name, c.symbol, c.priceeur, c.percent_change_24h, EXISTS(SELECT 1 FROM usercoins u WHERE u.userid = w.userid AND u.symbol = c.symbol), w.notes, w.date_added FROM watchlist w INNER JOIN coins c ON c.coinid = w.coinid WHERE w.userid = $1 ORDER BY w.watchlistid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var watchlist []WatchlistEntry
+
+	// Foreach watchlist entry
+	for rows.Next() {
+		var entry WatchlistEntry
+
+		err = rows.Scan(&entry.WatchlistID, &entry.CoinID, &entry.Name, &entry.Symbol, &entry.PriceEur, &entry.PercentChange24h, &entry.Held, &entry.Notes, &entry.DateAdded)
+
+		if err != nil {
+			panic(err)
+		}
+
+		watchlist = append(watchlist, entry)
+	}
+
+	return watchlist
+}
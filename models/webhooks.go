@@ -0,0 +1,496 @@
+package models
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/karolispx/golang-crypto-portfolio/helpers"
+	_ "github.com/lib/pq"
+)
+
+// WebhookPayloadVersion - version of the JSON body sent to webhooks
+const WebhookPayloadVersion = 1
+
+// WebhookSignatureHeader - header with HMAC-SHA256 of the body, signed with webhook's secret
+const WebhookSignatureHeader = "X-Portfolio-Signature"
+
+// WebhookMaxAttempts - failed deliveries are retried with exponential backoff up to this many attempts
+const WebhookMaxAttempts = 8
+
+// WebhookEvents - events webhooks can subscribe to
+var WebhookEvents = []string{"alert.fired", "sync.completed", "holding.changed", "daily.summary", "portfolio.digest"}
+
+// Webhook - URL registered by the user to receive events
+type Webhook struct {
+	WebhookID int      `json:"webhookid"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"`
+	Events    []string `json:"events"`
+	Enabled   bool     `json:"enabled"`
+	DateAdded string   `json:"date_added"`
+}
+
+// WebhookPayload - versioned body of every webhook request
+type WebhookPayload struct {
+	Version int         `json:"version"`
+	Event   string      `json:"event"`
+	Created string      `json:"created"`
+	Data    interface{} `json:"data"`
+}
+
+// WebhookDelivery - single event sent (or to be sent) to a webhook
+type WebhookDelivery struct {
+	WebhookDeliveryID int    `json:"webhookdeliveryid"`
+	WebhookID         int    `json:"webhookid"`
+	Event             string `json:"event"`
+	Payload           string `json:"payload"`
+	Status            string `json:"status"`
+	Attempts          int    `json:"attempts"`
+	StatusCode        int    `json:"status_code"`
+	LastError         string `json:"last_error"`
+	NextAttempt       string `json:"next_attempt"`
+	DateAdded         string `json:"date_added"`
+	DateDelivered     string `json:"date_delivered"`
+}
+
+// webhookClient - HTTP client used for deliveries, it only connects to public addresses unless AllowPrivateOutbound is set
+// The address is checked when connecting, so hosts that resolve differently later and redirects are covered too.
+var webhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: outboundDialControl}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// webhookDeliveryColumns - delivery columns in the order they are scanned by scanWebhookDelivery
+const webhookDeliveryColumns = "webhookdeliveryid, webhookid, event, payload, status, attempts, status_code, last_error, next_attempt, date_added, date_delivered"
+
+// ValidateOutboundURL - check that URL is http or https and its host only resolves to public addresses
+// Users see status codes and errors of requests sent to their URLs, so they can not point them at internal hosts.
+// AllowPrivateOutbound in config.json lifts the address check, so local stand-ins can be used while developing.
+func ValidateOutboundURL(rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Hostname() == "" {
+		return errors.New("Please provide a valid http or https URL.")
+	}
+
+	ips, err := net.LookupIP(parsedURL.Hostname())
+
+	if err != nil || len(ips) == 0 {
+		return errors.New("Host " + parsedURL.Hostname() + " could not be resolved.")
+	}
+
+	for _, ip := range ips {
+		if !outboundIPAllowed(ip) {
+			return errors.New("URL can not point to a local or private address.")
+		}
+	}
+
+	return nil
+}
+
+// isPublicIP - check that address is not loopback, private, link-local, multicast or unspecified
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// outboundIPAllowed - check that outbound requests can go to the address
+func outboundIPAllowed(ip net.IP) bool {
+	return isPublicIP(ip) || helpers.GetConfig().AllowPrivateOutbound
+}
+
+// outboundDialControl - refuse connections to addresses that are not public
+func outboundDialControl(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !outboundIPAllowed(ip) {
+		return errors.New("connections to local or private addresses are not allowed")
+	}
+
+	return nil
+}
+
+// ValidateWebhook - check webhook URL and events
+func ValidateWebhook(webhook Webhook) error {
+	if err := ValidateOutboundURL(webhook.URL); err != nil {
+		return err
+	}
+
+	if len(webhook.Events) == 0 {
+		return errors.New("Please choose at least one event.")
+	}
+
+	for _, event := range webhook.Events {
+		if event == "*" {
+			continue
+		}
+
+		eventExists := false
+
+		for _, webhookEvent := range WebhookEvents {
+			if event == webhookEvent {
+				eventExists = true
+			}
+		}
+
+		if !eventExists {
+			return errors.New("Event " + event + " does not exist.")
+		}
+	}
+
+	return nil
+}
+
+// GenerateWebhookSecret - random secret used to sign webhook payloads
+func GenerateWebhookSecret() string {
+	secret := make([]byte, 32)
+
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(secret)
+}
+
+// SignWebhookPayload - HMAC-SHA256 of the payload, as sent in WebhookSignatureHeader
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook - register new webhook
+func CreateWebhook(DB *sql.DB, userID int, webhook Webhook) int {
+	lastInsertID := 0
+
+	err := DB.QueryRow("INSERT INTO webhooks(userid, url, secret, events, enabled, date_added) VALUES($1, $2, $3, $4, $5, $6) returning webhookid;",
+		userID, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Enabled, helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// UpdateWebhook - update webhook URL, events and whether it is enabled
+func UpdateWebhook(DB *sql.DB, userID int, webhook Webhook) int {
+	lastUpdatedID := 0
+
+	err := DB.QueryRow("UPDATE webhooks SET url = $1, events = $2, enabled = $3 WHERE webhookid = $4 AND userid = $5 returning webhookid;",
+		webhook.URL, strings.Join(webhook.Events, ","), webhook.Enabled, webhook.WebhookID, userID).Scan(&lastUpdatedID)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	return lastUpdatedID
+}
+
+// RemoveWebhook - delete webhook and its delivery log
+func RemoveWebhook(DB *sql.DB, userID int, webhookID int) bool {
+	result, err := DB.Exec("DELETE FROM webhooks WHERE webhookid = $1 AND userid = $2", webhookID, userID)
+
+	if err != nil {
+		return false
+	}
+
+	if removed, _ := result.RowsAffected(); removed < 1 {
+		return false
+	}
+
+	_, err = DB.Exec("DELETE FROM webhookdeliveries WHERE webhookid = $1", webhookID)
+
+	return err == nil
+}
+
+// GetUserWebhook - get single webhook of the user, WebhookID is 0 if it does not exist
+func GetUserWebhook(DB *sql.DB, userID int, webhookID int) Webhook {
+	var webhook Webhook
+	var events string
+
+	err := DB.QueryRow("SELECT webhookid, url, secret, events, enabled, date_added FROM webhooks WHERE webhookid = $1 AND userid = $2", webhookID, userID).Scan(
+		&webhook.WebhookID, &webhook.URL, &webhook.Secret, &events, &webhook.Enabled, &webhook.DateAdded)
+
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+
+	webhook.Events = splitWebhookEvents(events)
+
+	return webhook
+}
+
+// GetUserWebhooks - get all webhooks of the user
+func GetUserWebhooks(DB *sql.DB, userID int) []Webhook {
+	rows, err := DB.Query("SELECT webhookid, url, secret, events, enabled, date_added FROM webhooks WHERE userid = $1 ORDER BY webhookid", userID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var webhooks []Webhook
+
+	// Foreach webhook
+	for rows.Next() {
+		var webhook Webhook
+		var events string
+
+		err = rows.Scan(&webhook.WebhookID, &webhook.URL, &webhook.Secret, &events, &webhook.Enabled, &webhook.DateAdded)
+
+		if err != nil {
+			panic(err)
+		}
+
+		webhook.Events = splitWebhookEvents(events)
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks
+}
+
+// GetWebhookDeliveries - latest deliveries of the webhook, newest first
+func GetWebhookDeliveries(DB *sql.DB, webhookID int, limit int) []WebhookDelivery {
+	rows, err := DB.Query("SELECT "+webhookDeliveryColumns+" FROM webhookdeliveries WHERE webhookid = $1 ORDER BY webhookdeliveryid DESC LIMIT $2", webhookID, limit)
+
+	if err != nil {
+		panic(err)
+	}
+
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+
+	// Foreach delivery
+	for rows.Next() {
+		var delivery WebhookDelivery
+
+		if err = scanWebhookDelivery(rows, &delivery); err != nil {
+			panic(err)
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries
+}
+
+// QueueWebhookEvent - queue event for every enabled webhook of the user subscribed to it
+func QueueWebhookEvent(DB *sql.DB, userID int, event string, data interface{}) {
+	for _, webhook := range GetUserWebhooks(DB, userID) {
+		if !webhook.Enabled || !webhookSubscribed(webhook, event) {
+			continue
+		}
+
+		QueueWebhookDelivery(DB, userID, webhook.WebhookID, event, data)
+	}
+}
+
+// QueueWebhookDelivery - store delivery of the event to the webhook, it is sent by DeliverWebhooks
+func QueueWebhookDelivery(DB *sql.DB, userID int, webhookID int, event string, data interface{}) int {
+	return createWebhookDelivery(DB, userID, webhookID, event, data, "pending")
+}
+
+// SendWebhookTest - send test event to the webhook right away, it is not queued and not retried
+func SendWebhookTest(DB *sql.DB, userID int, webhookID int, data interface{}) WebhookDelivery {
+	return DeliverWebhook(DB, createWebhookDelivery(DB, userID, webhookID, "webhook.test", data, "sending"))
+}
+
+// createWebhookDelivery - store delivery with the status, only pending ones are picked up by DeliverWebhooks
+func createWebhookDelivery(DB *sql.DB, userID int, webhookID int, event string, data interface{}, status string) int {
+	payload, err := json.Marshal(WebhookPayload{
+		Version: WebhookPayloadVersion,
+		Event:   event,
+		Created: helpers.GetCurrentDateTime(),
+		Data:    data,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	lastInsertID := 0
+
+	err = DB.QueryRow("INSERT INTO webhookdeliveries(webhookid, userid, event, payload, status, next_attempt, date_added) VALUES($1, $2, $3, $4, $5, $6, $7) returning webhookdeliveryid;",
+		webhookID, userID, event, string(payload), status, helpers.GetCurrentDateTime(), helpers.GetCurrentDateTime()).Scan(&lastInsertID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return lastInsertID
+}
+
+// DeliverWebhooks - send pending webhook deliveries that are due
+func DeliverWebhooks(minutes int, DB *sql.DB) {
+	d := time.Duration(minutes) * time.Minute
+
+	for range time.Tick(d) {
+		rows, err := DB.Query("SELECT webhookdeliveryid FROM webhookdeliveries WHERE status = $1 AND next_attempt <= $2 ORDER BY webhookdeliveryid", "pending", helpers.GetCurrentDateTime())
+
+		if err != nil {
+			panic(err)
+		}
+
+		var deliveryIDs []int
+
+		// Foreach delivery
+		for rows.Next() {
+			var deliveryID int
+
+			if err = rows.Scan(&deliveryID); err != nil {
+				panic(err)
+			}
+
+			deliveryIDs = append(deliveryIDs, deliveryID)
+		}
+
+		rows.Close()
+
+		for _, deliveryID := range deliveryIDs {
+			DeliverWebhook(DB, deliveryID)
+		}
+	}
+}
+
+// DeliverWebhook - send single delivery and store the outcome
+// Failed pending deliveries are retried after 1, 2, 4... minutes until WebhookMaxAttempts is reached,
+// test deliveries fail right away. Deliveries to webhooks disabled since they were queued are skipped.
+func DeliverWebhook(DB *sql.DB, deliveryID int) WebhookDelivery {
+	var delivery WebhookDelivery
+	var webhookURL string
+	var secret string
+	var enabled bool
+
+	err := DB.QueryRow("SELECT d.webhookdeliveryid, d.webhookid, d.event, d.payload, d.status, d.attempts, d.status_code, d.last_error, d.next_attempt, d.date_added, d.date_delivered, w.url, w.secret, w.enabled FROM webhookdeliveries d INNER JOIN webhooks w ON w.webhookid = d.webhookid WHERE d.webhookdeliveryid = $1", deliveryID).Scan(
+		&delivery.WebhookDeliveryID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.StatusCode,
+		&delivery.LastError, &delivery.NextAttempt, &delivery.DateAdded, &delivery.DateDelivered, &webhookURL, &secret, &enabled)
+
+	if err == sql.ErrNoRows {
+		return delivery
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	if !enabled {
+		delivery.Status = "skipped"
+		delivery.LastError = "Webhook is disabled."
+
+		_, err = DB.Exec("UPDATE webhookdeliveries SET status = $1, last_error = $2 WHERE webhookdeliveryid = $3", delivery.Status, delivery.LastError, delivery.WebhookDeliveryID)
+
+		if err != nil {
+			panic(err)
+		}
+
+		return delivery
+	}
+
+	retry := delivery.Status == "pending"
+
+	delivery.Attempts++
+	delivery.StatusCode, delivery.LastError = postWebhook(webhookURL, secret, delivery.Event, []byte(delivery.Payload))
+
+	if delivery.LastError == "" {
+		delivery.Status = "delivered"
+		delivery.DateDelivered = helpers.GetCurrentDateTime()
+	} else if !retry || delivery.Attempts >= WebhookMaxAttempts {
+		delivery.Status = "failed"
+	} else {
+		backoff := time.Duration(math.Pow(2, float64(delivery.Attempts-1))) * time.Minute
+		delivery.NextAttempt = helpers.FormatDateTime(time.Now().Add(backoff))
+	}
+
+	_, err = DB.Exec("UPDATE webhookdeliveries SET status = $1, attempts = $2, status_code = $3, last_error = $4, next_attempt = $5, date_delivered = $6 WHERE webhookdeliveryid = $7",
+		delivery.Status, delivery.Attempts, delivery.StatusCode, delivery.LastError, delivery.NextAttempt, delivery.DateDelivered, delivery.WebhookDeliveryID)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return delivery
+}
+
+// postWebhook - POST signed payload, returns status code and error message (empty on 2xx)
+func postWebhook(webhookURL string, secret string, event string, payload []byte) (int, string) {
+	request, err := http.NewRequest("POST", webhookURL, bytes.NewReader(payload))
+
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	request.Header.Set("User-Agent", helpers.GetConfig().APPName+" Webhooks")
+	request.Header.Set("X-Portfolio-Event", event)
+	request.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, payload))
+
+	response, err := webhookClient.Do(request)
+
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	defer response.Body.Close()
+
+	ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, "Webhook responded with status " + strconv.Itoa(response.StatusCode) + "."
+	}
+
+	return response.StatusCode, ""
+}
+
+// webhookSubscribed - check if webhook receives the event
+func webhookSubscribed(webhook Webhook, event string) bool {
+	for _, webhookEvent := range webhook.Events {
+		if webhookEvent == event || webhookEvent == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitWebhookEvents - events are stored comma separated
+func splitWebhookEvents(events string) []string {
+	if events == "" {
+		return []string{}
+	}
+
+	return strings.Split(events, ",")
+}
+
+// scanWebhookDelivery - scan webhookDeliveryColumns into delivery
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }, delivery *WebhookDelivery) error {
+	return row.Scan(&delivery.WebhookDeliveryID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts,
+		&delivery.StatusCode, &delivery.LastError, &delivery.NextAttempt, &delivery.DateAdded, &delivery.DateDelivered)
+}